@@ -13,6 +13,7 @@ import (
 
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/aggregator"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/coordinator"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/web"
 )
@@ -22,7 +23,7 @@ var (
 	configFile   = flag.String("config", "./configs/config.yaml", "Path to configuration file")
 	sourcesFile  = flag.String("sources", "./configs/sources.yaml", "Path to sources configuration file")
 	outputFile   = flag.String("output", "", "Output file path")
-	outputFormat = flag.String("format", "", "Output format (json, csv, html, xml)")
+	outputFormat = flag.String("format", "", "Output format (json, yaml, csv, html, xml)")
 	logLevel     = flag.String("log-level", "", "Log level (debug, info, warn, error)")
 	enableWeb    = flag.Bool("web", false, "Enable web interface")
 	webPort      = flag.Int("port", 0, "Web/API server port")
@@ -39,9 +40,19 @@ var (
 )
 
 func main() {
-	// Parse command-line flags
+	// Parse command-line flags; "aggregator daemon" runs the sources on their
+	// schedules instead of once, and the flags may come before or after it
 	flag.Parse()
 
+	daemon := flag.Arg(0) == "daemon"
+	if daemon {
+		flag.CommandLine.Parse(flag.Args()[1:])
+
+		if flag.NArg() > 0 {
+			log.Fatalf("Unexpected argument after daemon: %s", flag.Arg(0))
+		}
+	}
+
 	// Show version and exit if requested
 	if *version {
 		fmt.Printf("Web Content Aggregator %s\n", Version)
@@ -90,6 +101,11 @@ func main() {
 		go startAPIServer(cfg, agg)
 	}
 
+	if daemon {
+		runDaemon(ctx, cfg, agg)
+		return
+	}
+
 	// Run the aggregation process
 	startTime := time.Now()
 	log.Println("Starting content aggregation...")
@@ -97,7 +113,10 @@ func main() {
 	results, err := agg.Run(ctx)
 
 	if err != nil {
-		log.Fatalf("Aggregation failed: %v", err)
+		if results == nil {
+			log.Fatalf("Aggregation failed: %v", err)
+		}
+		log.Printf("Warning: %v; writing the %d items collected so far", err, len(results.Items))
 	}
 
 	// Output the results
@@ -107,8 +126,8 @@ func main() {
 
 	elaspedTime := time.Since(startTime)
 
-	log.Fatalf("Aggregation completed in %v. Processed %d sources, retrieved %d items.",
-		elaspedTime, len(cfg.Sources.Sources), len(results))
+	log.Printf("Aggregation completed in %v. Processed %d sources (%d succeeded, %d failed), retrieved %d items.",
+		elaspedTime, results.SourceCount, results.SuccessfulCount, results.FailedCount, len(results.Items))
 }
 
 // Applies command-line flag overrides to the configuration
@@ -216,8 +235,24 @@ func startAPIServer(cfg *config.Config, agg *aggregator.Aggregator) {
 	log.Printf("API server started on http://%s:%d", cfg.API.Host, cfg.API.Port)
 }
 
+// runDaemon runs the sources on their schedules until a signal arrives,
+// writing the latest results of every source after each run
+func runDaemon(ctx context.Context, cfg *config.Config, agg *aggregator.Aggregator) {
+	log.Println("Starting content aggregation daemon...")
+
+	err := agg.Daemon(ctx, func(results *model.AggregatedResults) {
+		if err := outputResults(cfg, results); err != nil {
+			log.Printf("Failed to output results: %v", err)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("Daemon failed: %v", err)
+	}
+}
+
 // outputResults handles writing the aggregation results to the configured destination
-func outputResults(cfg *config.Config, results []aggregator.Result) error {
+func outputResults(cfg *config.Config, results *model.AggregatedResults) error {
 	switch cfg.App.Output.Destination {
 	case "file":
 		if cfg.App.Output.FilePath == "" {
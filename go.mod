@@ -2,4 +2,7 @@ module github.com/CyberwizD/Concurrent-Web-Content-Aggregator
 
 go 1.24.0
 
-require github.com/temoto/robotstxt v1.1.2
+require (
+	github.com/temoto/robotstxt v1.1.2
+	gopkg.in/yaml.v3 v3.0.1
+)
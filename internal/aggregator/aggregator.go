@@ -2,32 +2,51 @@ package aggregator
 
 import (
 	"context"
-	"encoding/csv"
-	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"fmt"
-	"html/template"
-	"os"
+	"log"
+	"sync"
+	"time"
 
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/coordinator"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
 )
 
 // ErrInvalidAggregator is returned when an invalid aggregator is provided
 var ErrInvalidAggregator = errors.New("aggregator cannot be nil")
 
+// ErrDaemonRunning is returned by Run while a daemon holds the pipeline
+var ErrDaemonRunning = errors.New("aggregator is running as a daemon")
+
 type Aggregator struct {
 	Coordinator *coordinator.Coordinator
 	Config      *config.Config
 	Content     string
+
+	// Sources converted from the configuration on first use; the same
+	// pointers are reused across runs
+	sources []*model.Source
+	invalid int // Enabled sources skipped because their settings are invalid
+
+	// Runs share the coordinator and so cannot overlap, nor overlap a daemon
+	runMu sync.Mutex
+
+	// The running daemon, if any; set before the daemon takes runMu
+	daemon *daemon
+	mu     sync.Mutex
+}
+
+// sourceTally counts the fetch and parse outcomes of one source in a run
+type sourceTally struct {
+	fetched, fetchFailed int
+	parsed, parseFailed  int
 }
 
-type Result struct {
-	// Add fields to store results of the aggregation
-	AggregatedContent []string
-	Statistics        map[string]int
-	Errors            []error
+// succeeded reports whether the source yielded anything: at least one fetch
+// succeeded and, when something was parsed, not every parse failed
+func (t *sourceTally) succeeded() bool {
+	return t != nil && t.fetched > 0 && (t.parsed > 0 || t.parseFailed == 0)
 }
 
 // New creates a new Aggregator instance with the provided configuration and coordinator.
@@ -46,91 +65,165 @@ func New(cfg *config.Config, coord *coordinator.Coordinator) (*Aggregator, error
 	}, nil
 }
 
-func (a *Aggregator) Run(ctx context.Context) ([]Result, error) {
-	// Start the coordinator
-	if err := a.Coordinator.Start(ctx); err != nil {
-		return nil, err
+// Sources returns the enabled sources of the configuration as the pipeline
+// models them. Sources whose settings do not validate are logged and left out.
+func (a *Aggregator) Sources() []*model.Source {
+	if a.sources != nil {
+		return a.sources
 	}
 
-	// Wait for the coordinator to finish
-	if err := a.Coordinator.Wait(); err != nil {
-		return nil, err
+	a.sources = make([]*model.Source, 0, len(a.Config.Sources.Sources))
+	a.invalid = 0
+
+	for i := range a.Config.Sources.Sources {
+		cfgSource := &a.Config.Sources.Sources[i]
+		if !cfgSource.Enabled {
+			continue
+		}
+
+		source := &model.Source{}
+		if err := cfgSource.Decode(source); err != nil {
+			log.Printf("Warning: Skipping source %s: %v", cfgSource.ID, err)
+			a.invalid++
+			continue
+		}
+
+		source.ID = cfgSource.ID
+
+		// The source type doubles as the parser type
+		if source.Parser == "" {
+			source.Parser = cfgSource.Type
+		}
+
+		if err := source.Validate(); err != nil {
+			log.Printf("Warning: Skipping source %s: %v", cfgSource.ID, err)
+			a.invalid++
+			continue
+		}
+
+		a.sources = append(a.sources, source)
 	}
 
-	// Return the results
-	return []Result{}, nil
+	return a.sources
 }
 
-// WriteResultsToFile writes the aggregation results to a file in the specified format
-func WriteResultsToFile(filePath string, results []Result, format string) error {
-	// TODO: Implement file writing logic based on the format
-	f, err := os.Create(filePath)
+// Run fetches and parses every enabled source, following pagination, sitemaps
+// and detail pages, and returns the collected items once the pipeline has
+// drained. The run is given app.timeouts.total: when that passes, outstanding
+// work is cancelled and the items collected so far are returned, marked as
+// incomplete. When ctx is cancelled first, they are returned along with the
+// error. Run waits for another run to finish, but fails with ErrDaemonRunning
+// while a daemon is running.
+func (a *Aggregator) Run(ctx context.Context) (*model.AggregatedResults, error) {
+	a.mu.Lock()
+	if a.daemon != nil {
+		a.mu.Unlock()
+		return nil, ErrDaemonRunning
+	}
+	a.runMu.Lock()
+	a.mu.Unlock()
+	defer a.runMu.Unlock()
 
-	if err != nil {
-		return err
+	sources := a.Sources()
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no enabled sources to aggregate")
 	}
 
-	defer f.Close()
-
-	switch format {
-	case "json":
-		encoder := json.NewEncoder(f)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(results)
-	case "csv":
-		writer := csv.NewWriter(f)
-		defer writer.Flush()
-		writer.Write([]string{"Content", "Error Count"})
-
-		for _, res := range results {
-			content := ""
-			if len(res.AggregatedContent) > 0 {
-				content = res.AggregatedContent[0] // simplified
+	parent := ctx
+	if total := a.Config.App.Timeouts.Total; total > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, total)
+		defer cancel()
+	}
+
+	if err := a.Coordinator.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	results := model.NewAggregatedResults()
+	tallies := make(map[*model.Source]*sourceTally, len(sources))
+	var fetchTime time.Duration
+	var mu sync.Mutex
+	var collectors sync.WaitGroup
+
+	tally := func(source *model.Source) *sourceTally {
+		t, ok := tallies[source]
+		if !ok {
+			t = &sourceTally{}
+			tallies[source] = t
+		}
+		return t
+	}
+
+	fetchResults := a.Coordinator.GetFetchResults()
+	parseResults := a.Coordinator.GetParseResults()
+	collectors.Add(2)
+
+	go func() {
+		defer collectors.Done()
+
+		for result := range fetchResults {
+			mu.Lock()
+			fetchTime += result.Duration
+			if result.Error != nil {
+				tally(result.Source).fetchFailed++
+				log.Printf("Fetch failed for %s: %v", result.Source.Name, result.Error)
+			} else {
+				tally(result.Source).fetched++
+			}
+			mu.Unlock()
+		}
+	}()
+
+	go func() {
+		defer collectors.Done()
+
+		for result := range parseResults {
+			mu.Lock()
+			if result.Error != nil {
+				tally(result.Source).parseFailed++
+				log.Printf("Parse failed for %s: %v", result.Source.Name, result.Error)
+			} else {
+				tally(result.Source).parsed++
 			}
-			writer.Write([]string{content, fmt.Sprintf("%d", len(res.Errors))})
+			results.AddItems(result.Results)
+			mu.Unlock()
 		}
-	case "xml":
-		encoder := xml.NewEncoder(f)
-		return encoder.Encode(results)
-	case "html":
-		tmpl := `<html><body><h1>Aggregated Results</h1>{{range .}}<p>{{index .AggregatedContent 0}}</p>{{end}}</body></html>`
-		t := template.Must(template.New("results").Parse(tmpl))
-		return t.Execute(f, results)
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
+	}()
+
+	for _, source := range sources {
+		a.Coordinator.SubmitFetchJob(source)
 	}
 
-	return nil
-}
+	waitErr := a.Coordinator.Wait()
 
-func WriteResultsToStdout(results []Result, format string) error {
-	// TODO: Implement stdout writing logic based on the format
-	switch format {
-	case "json":
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(results)
-	case "csv":
-		writer := csv.NewWriter(os.Stdout)
-		defer writer.Flush()
-		writer.Write([]string{"Content", "Error Count"})
-		for _, res := range results {
-			content := ""
-			if len(res.AggregatedContent) > 0 {
-				content = res.AggregatedContent[0]
-			}
-			writer.Write([]string{content, fmt.Sprintf("%d", len(res.Errors))})
+	// Stopping closes the result channels, which ends the collectors
+	a.Coordinator.Stop()
+	collectors.Wait()
+
+	successful := 0
+	for _, source := range sources {
+		if tallies[source].succeeded() {
+			successful++
 		}
-	case "xml":
-		encoder := xml.NewEncoder(os.Stdout)
-		return encoder.Encode(results)
-	case "html":
-		tmpl := `<html><body><h1>Aggregated Results</h1>{{range .}}<p>{{index .AggregatedContent 0}}</p>{{end}}</body></html>`
-		t := template.Must(template.New("results").Parse(tmpl))
-		return t.Execute(os.Stdout, results)
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
 	}
 
-	return nil
+	total := len(sources) + a.invalid
+	results.UpdateStats(total, successful, total-successful, fetchTime.Milliseconds())
+	results.CreatedAt = time.Now()
+	results.Incomplete = waitErr != nil
+
+	log.Printf("Aggregation run finished: %d of %d sources succeeded, %d items", successful, total, len(results.Items))
+
+	// Running out of the run's own time budget still yields a result
+	if waitErr != nil && parent.Err() == nil {
+		log.Printf("Warning: Aggregation stopped after %s, results are incomplete", a.Config.App.Timeouts.Total)
+		return results, nil
+	}
+
+	if waitErr != nil {
+		return results, fmt.Errorf("aggregation interrupted: %w", waitErr)
+	}
+
+	return results, nil
 }
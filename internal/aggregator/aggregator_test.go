@@ -0,0 +1,188 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/coordinator"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title>
+<item><title>First</title><link>https://example.com/1</link><guid>1</guid></item>
+<item><title>Second</title><link>https://example.com/2</link><guid>2</guid></item>
+</channel></rss>`
+
+// newTestAggregator serves testFeed and creates an aggregator for the given
+// sources, with %[1]s in the sources standing for the server's URL
+func newTestAggregator(t *testing.T, sources string) *Aggregator {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, testFeed)
+		case "/slow":
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf(sources, server.URL)), &cfg.Sources); err != nil {
+		t.Fatal(err)
+	}
+
+	coord, err := coordinator.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agg, err := New(cfg, coord)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return agg
+}
+
+func TestRun(t *testing.T) {
+	agg := newTestAggregator(t, `
+sources:
+  - id: feed
+    name: Feed
+    url: %[1]s/feed.xml
+    type: rss
+    enabled: true
+  - id: missing
+    name: Missing
+    url: %[1]s/missing.xml
+    type: rss
+    enabled: true
+  - id: disabled
+    name: Disabled
+    url: %[1]s/feed.xml
+    type: rss
+`)
+
+	// A second run reuses the coordinator and must report the same
+	for run := 1; run <= 2; run++ {
+		results, err := agg.Run(context.Background())
+		if err != nil {
+			t.Fatalf("run %d: Run() error = %v", run, err)
+		}
+
+		if results.SourceCount != 2 || results.SuccessfulCount != 1 || results.FailedCount != 1 {
+			t.Errorf("run %d: counts = %d/%d/%d, want 2/1/1", run, results.SourceCount, results.SuccessfulCount, results.FailedCount)
+		}
+
+		if len(results.Items) != 2 {
+			t.Errorf("run %d: got %d items, want 2", run, len(results.Items))
+		}
+
+		for _, item := range results.Items {
+			if item.SourceID != "feed" {
+				t.Errorf("run %d: item source ID = %q, want the configured id %q", run, item.SourceID, "feed")
+			}
+		}
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	agg := newTestAggregator(t, `
+sources:
+  - id: feed
+    name: Feed
+    url: %[1]s/feed.xml
+    type: rss
+    enabled: true
+  - id: slow
+    name: Slow
+    url: %[1]s/slow
+    type: rss
+    enabled: true
+`)
+	agg.Config.App.Timeouts.Total = 300 * time.Millisecond
+
+	start := time.Now()
+	results, err := agg.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v, want partial results", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %s, want it cut short after 300ms", elapsed)
+	}
+
+	if !results.Incomplete || len(results.Items) != 2 || results.SuccessfulCount != 1 {
+		t.Errorf("results: incomplete %v, %d items, %d succeeded; want incomplete with the 2 items of Feed", results.Incomplete, len(results.Items), results.SuccessfulCount)
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	agg := newTestAggregator(t, `
+sources:
+  - id: feed
+    name: Feed
+    url: %[1]s/feed.xml
+    type: rss
+    enabled: true
+    schedule: "@every 1s"
+  - id: unscheduled
+    name: Unscheduled
+    url: %[1]s/feed.xml
+    type: rss
+    enabled: true
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan *model.AggregatedResults, 10)
+	stopped := make(chan error)
+	go func() {
+		stopped <- agg.Daemon(ctx, func(results *model.AggregatedResults) { updates <- results })
+	}()
+
+	for run := 1; run <= 2; run++ {
+		select {
+		case results := <-updates:
+			// The latest run replaces the items of the one before
+			if len(results.Items) != 2 || results.SourceCount != 1 || results.SuccessfulCount != 1 {
+				t.Errorf("run %d: got %d items from %d/%d sources, want 2 from 1/1", run, len(results.Items), results.SuccessfulCount, results.SourceCount)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("run %d: no update from the daemon", run)
+		}
+	}
+
+	// A one-off run would wait for the daemon to stop
+	if _, err := agg.Run(ctx); !errors.Is(err, ErrDaemonRunning) {
+		t.Errorf("Run() during the daemon error = %v, want ErrDaemonRunning", err)
+	}
+
+	schedule := agg.Schedule()
+	if len(schedule) != 1 || schedule[0].Name != "Feed" || schedule[0].Next.IsZero() {
+		t.Errorf("Schedule() = %+v, want the next run of Feed", schedule)
+	}
+
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("Daemon() error = %v", err)
+	}
+
+	if _, running := agg.Latest(); running {
+		t.Error("Latest() reports a daemon after it stopped")
+	}
+}
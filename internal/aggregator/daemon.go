@@ -0,0 +1,298 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/scheduler"
+)
+
+// daemon is the state of a running Daemon
+type daemon struct {
+	scheduler *scheduler.Scheduler
+	sources   []*model.Source // Sources with a valid schedule
+
+	// Results of the runs in progress, kept by the collector
+	current map[*model.Source]*sourceResults
+
+	// Results of the last finished run of each source, and those combined
+	latest  map[*model.Source]*sourceResults
+	results *model.AggregatedResults
+	mu      sync.Mutex
+
+	// A scheduled run asks the collector to close it once its jobs are done
+	finished chan finishRequest
+}
+
+// sourceResults is what one run of a source produced
+type sourceResults struct {
+	items     []model.ResultItem
+	tally     sourceTally
+	fetchTime time.Duration
+}
+
+type finishRequest struct {
+	source *model.Source
+	reply  chan *model.AggregatedResults
+}
+
+// Daemon keeps the pipeline running and runs every enabled source on its own
+// schedule until ctx is cancelled. A source is not run again while its
+// previous run is still going. After each run onUpdate, if set, is called
+// with the latest results of every source.
+func (a *Aggregator) Daemon(ctx context.Context, onUpdate func(*model.AggregatedResults)) error {
+	d := &daemon{
+		scheduler: scheduler.New(),
+		current:   make(map[*model.Source]*sourceResults),
+		latest:    make(map[*model.Source]*sourceResults),
+		results:   model.NewAggregatedResults(),
+		finished:  make(chan finishRequest),
+	}
+
+	// Announce the daemon before waiting for any run in progress, so that
+	// a Run asked for meanwhile fails instead of waiting for the daemon
+	a.mu.Lock()
+	if a.daemon != nil {
+		a.mu.Unlock()
+		return fmt.Errorf("a daemon is already running")
+	}
+	a.daemon = d
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		a.daemon = nil
+		a.mu.Unlock()
+	}()
+
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	for _, source := range a.Sources() {
+		if source.Schedule == "" {
+			log.Printf("Warning: Source %s has no schedule, not running it in daemon mode", source.Name)
+			continue
+		}
+
+		source := source
+		err := d.scheduler.Add(source.Name, source.Schedule, func(ctx context.Context) {
+			a.runScheduled(ctx, d, source, onUpdate)
+		})
+		if err != nil {
+			log.Printf("Warning: Skipping source %s: %v", source.Name, err)
+			continue
+		}
+
+		d.sources = append(d.sources, source)
+	}
+
+	if len(d.sources) == 0 {
+		return fmt.Errorf("no enabled source has a valid schedule")
+	}
+
+	if err := a.Coordinator.Start(ctx); err != nil {
+		return err
+	}
+
+	var collector sync.WaitGroup
+	collector.Add(1)
+	go func() {
+		defer collector.Done()
+		d.collect(a.Coordinator.GetFetchResults(), a.Coordinator.GetParseResults())
+	}()
+
+	log.Printf("Daemon started with %d scheduled sources", len(d.sources))
+	for _, entry := range d.scheduler.Entries() {
+		log.Printf("Next run of %s (%s) at %s", entry.Name, entry.Spec, entry.Next.Format(time.RFC3339))
+	}
+
+	// Returns once ctx is cancelled and the runs in progress have returned
+	d.scheduler.Run(ctx)
+
+	a.Coordinator.Stop()
+	collector.Wait()
+
+	log.Printf("Daemon stopped")
+
+	return nil
+}
+
+// Latest returns the latest results of every source while Daemon is running.
+// The second result is false when no daemon is running.
+func (a *Aggregator) Latest() (*model.AggregatedResults, bool) {
+	a.mu.Lock()
+	d := a.daemon
+	a.mu.Unlock()
+
+	if d == nil {
+		return nil, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.results, true
+}
+
+// Schedule returns the scheduled sources with their next run times while
+// Daemon is running, soonest first, or nil when no daemon is running
+func (a *Aggregator) Schedule() []scheduler.Entry {
+	a.mu.Lock()
+	d := a.daemon
+	a.mu.Unlock()
+
+	if d == nil {
+		return nil
+	}
+
+	return d.scheduler.Entries()
+}
+
+// runScheduled runs a source once and waits for all its jobs to finish
+func (a *Aggregator) runScheduled(ctx context.Context, d *daemon, source *model.Source, onUpdate func(*model.AggregatedResults)) {
+	start := time.Now()
+	log.Printf("Starting scheduled run of %s", source.Name)
+
+	done := a.Coordinator.SubmitFetchJob(source)
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return
+	}
+
+	reply := make(chan *model.AggregatedResults, 1)
+	select {
+	case d.finished <- finishRequest{source: source, reply: reply}:
+	case <-ctx.Done():
+		return
+	}
+	results := <-reply
+
+	log.Printf("Scheduled run of %s finished in %v", source.Name, time.Since(start).Round(time.Millisecond))
+
+	if onUpdate != nil {
+		onUpdate(results)
+	}
+}
+
+// collect records fetch and parse results by source until both channels are
+// closed, and closes the runs of the sources whose jobs are done
+func (d *daemon) collect(fetchResults <-chan *model.FetchResult, parseResults <-chan *model.ParseResult) {
+	for fetchResults != nil || parseResults != nil {
+		select {
+		case result, ok := <-fetchResults:
+			if !ok {
+				fetchResults = nil
+				continue
+			}
+			d.recordFetch(result)
+		case result, ok := <-parseResults:
+			if !ok {
+				parseResults = nil
+				continue
+			}
+			d.recordParse(result)
+		case req := <-d.finished:
+			// Every result of the source was sent before its jobs were
+			// marked done, so any not received yet are still buffered
+			d.drain(fetchResults, parseResults)
+			req.reply <- d.finish(req.source)
+		}
+	}
+}
+
+// drain records the results waiting in the channels without blocking
+func (d *daemon) drain(fetchResults <-chan *model.FetchResult, parseResults <-chan *model.ParseResult) {
+	for {
+		select {
+		case result, ok := <-fetchResults:
+			if !ok {
+				fetchResults = nil
+				continue
+			}
+			d.recordFetch(result)
+		case result, ok := <-parseResults:
+			if !ok {
+				parseResults = nil
+				continue
+			}
+			d.recordParse(result)
+		default:
+			return
+		}
+	}
+}
+
+func (d *daemon) run(source *model.Source) *sourceResults {
+	run, ok := d.current[source]
+	if !ok {
+		run = &sourceResults{}
+		d.current[source] = run
+	}
+	return run
+}
+
+func (d *daemon) recordFetch(result *model.FetchResult) {
+	run := d.run(result.Source)
+	run.fetchTime += result.Duration
+
+	if result.Error != nil {
+		run.tally.fetchFailed++
+		log.Printf("Fetch failed for %s: %v", result.Source.Name, result.Error)
+	} else {
+		run.tally.fetched++
+	}
+}
+
+func (d *daemon) recordParse(result *model.ParseResult) {
+	run := d.run(result.Source)
+
+	if result.Error != nil {
+		run.tally.parseFailed++
+		log.Printf("Parse failed for %s: %v", result.Source.Name, result.Error)
+	} else {
+		run.tally.parsed++
+	}
+
+	run.items = append(run.items, result.Results...)
+}
+
+// finish replaces the results of a source with those of its run that just
+// finished, and combines the latest results of every source
+func (d *daemon) finish(source *model.Source) *model.AggregatedResults {
+	run := d.run(source)
+	delete(d.current, source)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.latest[source] = run
+
+	results := model.NewAggregatedResults()
+	successful := 0
+	var fetchTime time.Duration
+
+	for _, s := range d.sources {
+		latest, ok := d.latest[s]
+		if !ok {
+			continue
+		}
+
+		results.AddItems(latest.items)
+		fetchTime += latest.fetchTime
+		if latest.tally.succeeded() {
+			successful++
+		}
+	}
+
+	// Sources that have not run yet count as neither succeeded nor failed
+	results.UpdateStats(len(d.sources), successful, len(d.latest)-successful, fetchTime.Milliseconds())
+	d.results = results
+
+	return results
+}
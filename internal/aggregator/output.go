@@ -0,0 +1,180 @@
+package aggregator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// WriteResultsToFile writes the aggregation results to a file in the specified format
+func WriteResultsToFile(filePath string, results *model.AggregatedResults, format string) error {
+	f, err := os.Create(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	if err := WriteResults(f, results, format); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// WriteResultsToStdout writes the aggregation results to standard output in the specified format
+func WriteResultsToStdout(results *model.AggregatedResults, format string) error {
+	return WriteResults(os.Stdout, results, format)
+}
+
+// WriteResults encodes the aggregation results as json, yaml, csv, xml or html
+func WriteResults(w io.Writer, results *model.AggregatedResults, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		defer encoder.Close()
+		return encoder.Encode(results)
+	case "csv":
+		return writeCSV(w, results)
+	case "xml":
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(newXMLResults(results)); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	case "html":
+		return resultsTemplate.Execute(w, results)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// csvHeader lists the columns of the CSV output; list fields are joined with "; "
+var csvHeader = []string{
+	"id", "source_id", "content_id", "type", "url", "title", "description",
+	"author", "timestamp", "categories", "tags", "images", "links", "content",
+}
+
+func writeCSV(w io.Writer, results *model.AggregatedResults) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, item := range results.Items {
+		err := writer.Write([]string{
+			item.ID, item.SourceID, item.ContentID, string(item.Type), item.URL,
+			item.Title, item.Description, item.Author, formatTimestamp(item.Timestamp),
+			strings.Join(item.Categories, "; "), strings.Join(item.Tags, "; "),
+			strings.Join(item.Images, "; "), strings.Join(item.Links, "; "), item.Content,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// xmlResults mirrors AggregatedResults for encoding/xml, which cannot encode
+// the free-form metadata maps; metadata is left out of the XML output
+type xmlResults struct {
+	XMLName          xml.Name  `xml:"results"`
+	ID               string    `xml:"id,attr"`
+	SourceCount      int       `xml:"source_count,attr"`
+	SuccessfulCount  int       `xml:"successful_count,attr"`
+	FailedCount      int       `xml:"failed_count,attr"`
+	TotalFetchTimeMs int64     `xml:"total_fetch_time_ms,attr"`
+	CreatedAt        time.Time `xml:"created_at,attr"`
+	Incomplete       bool      `xml:"incomplete,attr,omitempty"`
+	Items            []xmlItem `xml:"item"`
+}
+
+type xmlItem struct {
+	ID          string   `xml:"id,attr"`
+	SourceID    string   `xml:"source_id,attr"`
+	ContentID   string   `xml:"content_id,attr,omitempty"`
+	Type        string   `xml:"type,attr"`
+	URL         string   `xml:"url,omitempty"`
+	Title       string   `xml:"title,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Content     string   `xml:"content,omitempty"`
+	Author      string   `xml:"author,omitempty"`
+	Timestamp   string   `xml:"timestamp,omitempty"`
+	Categories  []string `xml:"category"`
+	Tags        []string `xml:"tag"`
+	Images      []string `xml:"image"`
+	Links       []string `xml:"link"`
+}
+
+func newXMLResults(results *model.AggregatedResults) xmlResults {
+	out := xmlResults{
+		ID:               results.ID,
+		SourceCount:      results.SourceCount,
+		SuccessfulCount:  results.SuccessfulCount,
+		FailedCount:      results.FailedCount,
+		TotalFetchTimeMs: results.TotalFetchTimeMs,
+		CreatedAt:        results.CreatedAt,
+		Incomplete:       results.Incomplete,
+		Items:            make([]xmlItem, len(results.Items)),
+	}
+
+	for i, item := range results.Items {
+		out.Items[i] = xmlItem{
+			ID:          item.ID,
+			SourceID:    item.SourceID,
+			ContentID:   item.ContentID,
+			Type:        string(item.Type),
+			URL:         item.URL,
+			Title:       item.Title,
+			Description: item.Description,
+			Content:     item.Content,
+			Author:      item.Author,
+			Timestamp:   formatTimestamp(item.Timestamp),
+			Categories:  item.Categories,
+			Tags:        item.Tags,
+			Images:      item.Images,
+			Links:       item.Links,
+		}
+	}
+
+	return out
+}
+
+var resultsTemplate = template.Must(template.New("results").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Aggregated Results</title></head>
+<body>
+<h1>Aggregated Results</h1>
+<p>{{len .Items}} items from {{.SourceCount}} sources ({{.SuccessfulCount}} succeeded, {{.FailedCount}} failed){{if .Incomplete}}; the run was cut short and the results are incomplete{{end}}</p>
+{{range .Items}}<article>
+<h2>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h2>
+<p><small>{{.SourceID}}{{if .Author}} &middot; {{.Author}}{{end}}{{if not .Timestamp.IsZero}} &middot; {{.Timestamp.Format "2006-01-02 15:04"}}{{end}}</small></p>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+</article>
+{{end}}</body></html>
+`))
@@ -10,6 +10,7 @@ import (
 
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/fetcher"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/normalizer"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/parser"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
 )
@@ -29,16 +30,33 @@ type Coordinator struct {
 	fetcherPool *WorkerPool
 	parserPool  *WorkerPool
 
-	// Fetcher and parsers
+	// Fetcher
 	fetcher *fetcher.Fetcher
-	parsers map[string]parser.Parser
 
-	// Channels for data flow
-	fetchJobs    chan *model.FetchJob
+	// Converts parsed items into result items
+	normalizer *normalizer.Normalizer
+
+	// Priority queues of jobs and channels of results
+	fetchJobs    *jobQueue[*model.FetchJob]
 	fetchResults chan *model.FetchResult
-	parseJobs    chan *model.ParseJob
+	parseJobs    *jobQueue[*model.ParseJob]
 	parseResults chan *model.ParseResult
 
+	// Pagination state per source for the current run
+	pages map[*model.Source]*pageRun
+
+	// Detail pages fetched per source in the current run
+	details map[*model.Source]int
+
+	// Jobs of the current run not yet finished, counting the pages, sitemap
+	// entries and detail pages they lead to; idle is signalled at zero
+	pending int
+	idle    chan struct{}
+	runCtx  context.Context
+
+	// Unfinished jobs per submitted source
+	sourceRuns map[*model.Source]*sourceRun
+
 	// Statistics
 	stats *Stats
 
@@ -46,6 +64,13 @@ type Coordinator struct {
 	mu sync.Mutex
 }
 
+// sourceRun tracks the unfinished jobs of a submitted source; done is closed
+// once they are all finished
+type sourceRun struct {
+	pending int
+	done    chan struct{}
+}
+
 // Stats tracks statistics about the aggregation process
 type Stats struct {
 	TotalSources      int
@@ -54,8 +79,12 @@ type Stats struct {
 	FailedFetches     int
 	SuccessfulParses  int
 	FailedParses      int
-	StartTime         time.Time
-	EndTime           time.Time
+	Retries           int // Fetch attempts repeated after a transient failure
+
+	// Circuit breaker of every host fetched so far
+	Circuits  map[string]fetcher.CircuitStatus
+	StartTime time.Time
+	EndTime   time.Time
 }
 
 // New creates a new coordinator with the provided configuration
@@ -71,48 +100,69 @@ func New(cfg *config.Config) (*Coordinator, error) {
 		return nil, fmt.Errorf("failed to create fetcher: %v", err)
 	}
 
-	// Create parsers for each type
-	parsers := make(map[string]parser.Parser)
-
-	for _, parseType := range []string{"html", "json", "xml", "rss"} {
-		p, err := parser.Get(parseType)
-
-		if err != nil {
-			return nil, fmt.Errorf("failed to create parser for type %s: %v", parseType, err)
-		}
-
-		parsers[parseType] = *p
-	}
-
-	// Create data channels
-	fetchJobChan := make(chan *model.FetchJob, cfg.App.Concurrency.MaxFetchers)
-	fetchResultChan := make(chan *model.FetchResult, cfg.App.Concurrency.MaxFetchers)
-	parseJobChan := make(chan *model.ParseJob, cfg.App.Concurrency.MaxParsers)
-	parseResultChan := make(chan *model.ParseResult, cfg.App.Concurrency.MaxParsers)
-
 	// Create worker pools
 	fetcherPool := NewWorkerPool(cfg.App.Concurrency.MaxFetchers, "fetcher")
 	parserPool := NewWorkerPool(cfg.App.Concurrency.MaxParsers, "parser")
 
-	return &Coordinator{
-		config:       cfg,
-		fetcher:      f,
-		parsers:      parsers,
-		fetcherPool:  fetcherPool,
-		parserPool:   parserPool,
-		fetchJobs:    fetchJobChan,
-		fetchResults: fetchResultChan,
-		parseJobs:    parseJobChan,
-		parseResults: parseResultChan,
+	c := &Coordinator{
+		config:      cfg,
+		fetcher:     f,
+		fetcherPool: fetcherPool,
+		parserPool:  parserPool,
+		normalizer:  normalizer.New(),
+		pages:       make(map[*model.Source]*pageRun),
+		details:     make(map[*model.Source]int),
+		sourceRuns:  make(map[*model.Source]*sourceRun),
 		stats: &Stats{
 			TotalSources: len(cfg.Sources.Sources),
 			StartTime:    time.Now(),
 		},
-	}, nil
+	}
+	c.makeChannels()
+
+	return c, nil
+}
+
+// makeChannels creates the job queues and result channels for a run. The
+// fetch queue is unbounded, since fetch workers add pages and detail pages to
+// it; the parse queue holds fetched bodies and so is bounded, holding the
+// fetch workers back when the parsers fall behind.
+func (c *Coordinator) makeChannels() {
+	aging := c.config.App.Concurrency.PriorityAging
+	parseCapacity := c.config.App.Concurrency.MaxParsers
+	if parseCapacity <= 0 {
+		parseCapacity = 1
+	}
+
+	c.fetchJobs = newJobQueue[*model.FetchJob](0, aging)
+	c.fetchResults = make(chan *model.FetchResult, c.config.App.Concurrency.MaxFetchers)
+	c.parseJobs = newJobQueue[*model.ParseJob](parseCapacity, aging)
+	c.parseResults = make(chan *model.ParseResult, c.config.App.Concurrency.MaxParsers)
 }
 
-// Start initializes and starts all worker pools and processing pipelines
+// Start initializes and starts all worker pools and processing pipelines.
+// A stopped coordinator can be started again for another run, with fresh
+// channels and statistics; jobs are submitted after Start.
 func (c *Coordinator) Start(ctx context.Context) error {
+	if c.fetcherPool.IsRunning() {
+		return fmt.Errorf("coordinator is already running")
+	}
+
+	c.mu.Lock()
+	if c.runCtx != nil {
+		// Channels of a previous run were closed by Stop
+		c.makeChannels()
+	}
+	c.runCtx = ctx
+	c.pending = 0
+	c.sourceRuns = make(map[*model.Source]*sourceRun)
+	c.idle = make(chan struct{}, 1)
+	c.stats = &Stats{
+		TotalSources: len(c.config.Sources.Sources),
+		StartTime:    time.Now(),
+	}
+	c.mu.Unlock()
+
 	// Start fetcher workers
 	c.fetcherPool.Start(ctx, func(id int, ctx context.Context) {
 		c.fetchWorker(ctx, id)
@@ -131,15 +181,18 @@ func (c *Coordinator) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop gracefully shuts down all worker pools
+// Stop gracefully shuts down all worker pools and closes the channels, which
+// ends any loop reading the fetch and parse results
 func (c *Coordinator) Stop() {
 	// Stop worker pools
 	c.fetcherPool.Stop()
 	c.parserPool.Stop()
 
-	// Close channel
-	close(c.fetchJobs)
-	close(c.parseJobs)
+	// Close queues and channels
+	c.fetchJobs.Close()
+	c.parseJobs.Close()
+	close(c.fetchResults)
+	close(c.parseResults)
 
 	// Update end time
 	c.mu.Lock()
@@ -149,38 +202,67 @@ func (c *Coordinator) Stop() {
 	log.Printf("Coordinator stopped")
 }
 
-// Wait blocks until all fetch and parse jobs are completed
+// Wait blocks until every submitted job, and every page, sitemap entry and
+// detail page it led to, has been fetched and parsed, or until the context
+// given to Start is cancelled. The fetch and parse results must be read
+// meanwhile, or the workers block.
 func (c *Coordinator) Wait() error {
-	// Wait for all fetch jobs to complete
-	go func() {
-		for job := range c.fetchResults {
-			c.mu.Lock()
-			if job.Error != nil {
-				c.stats.FailedFetches++
-				log.Printf("Fetch failed for %s: %v", job.Source.URL, job.Error)
-			} else {
-				c.stats.SuccessfulFetches++
-				c.stats.ProcessedSources++
-			}
-			c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		pending, idle, ctx := c.pending, c.idle, c.runCtx
+		c.mu.Unlock()
+
+		if pending == 0 {
+			return nil
 		}
-	}()
-
-	// Wait for all parse jobs to complete
-	go func() {
-		for job := range c.parseResults {
-			c.mu.Lock()
-			if job.Error != nil {
-				c.stats.FailedParses++
-				log.Printf("Parse failed for %s: %v", job.Source.Name, job.Error)
-			} else {
-				c.stats.SuccessfulParses++
-			}
-			c.mu.Unlock()
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-	}()
+	}
+}
 
-	return nil
+// addPending records jobs of a source submitted in the current run, and
+// returns the channel closed once all the source's jobs are finished
+func (c *Coordinator) addPending(source *model.Source, n int) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending += n
+
+	run, ok := c.sourceRuns[source]
+	if !ok {
+		run = &sourceRun{done: make(chan struct{})}
+		c.sourceRuns[source] = run
+	}
+	run.pending += n
+
+	return run.done
+}
+
+// jobDone records that a job is finished: its parse result, or its fetch
+// result when there is nothing to parse, has been sent
+func (c *Coordinator) jobDone(source *model.Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if run, ok := c.sourceRuns[source]; ok {
+		run.pending--
+		if run.pending == 0 {
+			close(run.done)
+			delete(c.sourceRuns, source)
+		}
+	}
+
+	c.pending--
+	if c.pending == 0 {
+		select {
+		case c.idle <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // GetStats returns a copy of the current statistics
@@ -190,17 +272,40 @@ func (c *Coordinator) GetStats() Stats {
 
 	// Create a copy to avoid race condition
 	statsCopy := *c.stats
+	statsCopy.Circuits = c.fetcher.CircuitStatus()
 	return statsCopy
 }
 
-// SubmitFetchJob submits a source to be fetched
-func (c *Coordinator) SubmitFetchJob(source *model.Source) {
+// SubmitFetchJob submits a source to be fetched. For a paginated source this
+// is the first page; the following pages are submitted as each one is parsed.
+// The returned channel is closed once the source's jobs, including its pages,
+// sitemap entries and detail pages, are all finished; it is never closed if
+// the run is cancelled first.
+func (c *Coordinator) SubmitFetchJob(source *model.Source) <-chan struct{} {
 	job := &model.FetchJob{
 		Source:      source,
 		SubmittedAt: time.Now(),
 	}
 
-	c.fetchJobs <- job
+	if source.Pagination.Enabled && !source.Sitemap.Enabled {
+		job = c.firstPageJob(source)
+	}
+	job.Priority = source.Priority
+
+	// Detail fetches are capped per run
+	c.mu.Lock()
+	delete(c.details, source)
+	ctx := c.runCtx
+	c.mu.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	done := c.addPending(source, 1)
+	c.fetchJobs.Push(ctx, job, job.Priority)
+
+	return done
 }
 
 // GetFetchResults returns a channel for receiving fetch results
@@ -213,71 +318,105 @@ func (c *Coordinator) GetParseResults() <-chan *model.ParseResult {
 	return c.parseResults
 }
 
-// fetchWorker processes fetch jobs from the fetch jobs channel
+// Normalizer returns the stage that converts parsed items into result items,
+// for registering conversions specific to a parser type
+func (c *Coordinator) Normalizer() *normalizer.Normalizer {
+	return c.normalizer
+}
+
+// fetchWorker processes fetch jobs from the fetch queue, highest priority first
 func (c *Coordinator) fetchWorker(ctx context.Context, workerID int) {
 	log.Printf("Fetch worker %d started", workerID)
 
 	for {
+		job, ok := c.fetchJobs.Pop(ctx)
+		if !ok {
+			if ctx.Err() != nil {
+				log.Printf("Fetch worker %d stopping: context cancelled", workerID)
+			} else {
+				log.Printf("Fetch worker %d stopping: queue closed", workerID)
+			}
+			return
+		}
+
+		// Process the fetch job
+		result := c.processFetchJob(ctx, job, workerID)
+
+		// Send the result
 		select {
+		case c.fetchResults <- result:
+			// Result sent successfully
 		case <-ctx.Done():
-			log.Printf("Fetch worker %d stopping: context cancelled", workerID)
+			log.Printf("Fetch worker %d: context cancelled while sending result", workerID)
 			return
-		case job, ok := <-c.fetchJobs:
-			if !ok {
-				log.Printf("Fetch worker %d stopping channel closed", workerID)
-				return
-			}
+		}
 
-			// Process the fetch job
-			result := c.processFetchJob(ctx, job, workerID)
+		// A listing item waiting for its detail page is passed on even
+		// when the page could not be fetched
+		metadata := job.Metadata
+		if _, detail := metadata[detailItemKey]; detail && result.Error != nil {
+			metadata = map[string]interface{}{
+				detailItemKey:  metadata[detailItemKey],
+				"detail_error": result.Error.Error(),
+			}
+		}
 
-			// Send the result
-			select {
-			case c.fetchResults <- result:
-				// Result sent successfully
-			case <-ctx.Done():
-				log.Printf("Fetch worker %d: context cancelled while sending result", workerID)
-				return
+		// If fetch was successful, submit for parsing
+		if result.Error == nil && result.Content != nil || metadata["detail_error"] != nil {
+			parseJob := &model.ParseJob{
+				Source:      job.Source,
+				Content:     result.Content,
+				Priority:    job.Priority,
+				SubmittedAt: time.Now(),
+				Metadata:    metadata,
 			}
 
-			// If fetch was successful, submit for parsing
-			if result.Error == nil && result.Content != nil {
-				parseJob := &model.ParseJob{
-					Source:      job.Source,
-					Content:     result.Content,
-					SubmittedAt: time.Now(),
-				}
-
-				select {
-				case c.parseJobs <- parseJob:
-					// Parse job submitted successfully
-				case <-ctx.Done():
-					log.Printf("Fetch worker %d: context cancelled while submitting parse job", workerID)
-					return
-				}
+			// The parse worker finishes the job
+			if !c.parseJobs.Push(ctx, parseJob, parseJob.Priority) {
+				log.Printf("Fetch worker %d: context cancelled while submitting parse job", workerID)
+				return
 			}
+		} else {
+			c.jobDone(job.Source)
 		}
 	}
 }
 
 // processFetchJob handles the actual fetching of content
 func (c *Coordinator) processFetchJob(ctx context.Context, job *model.FetchJob, workerID int) *model.FetchResult {
-	log.Printf("worker %d fetching from %s", workerID, job.Source.URL)
+	// A sitemap source fans out into one job per listed page
+	if job.URL == "" && job.Source.Sitemap.Enabled {
+		return c.processSitemapJob(ctx, job, workerID)
+	}
 
-	// Create fetch-specific context with timeout
-	fetchCtx, cancel := context.WithTimeout(ctx, time.Duration(c.config.App.Timeouts.Request))
-	defer cancel()
+	target := job.Source.URL
+	if job.URL != "" {
+		target = job.URL
+	}
 
-	// Fetch the content
-	content, err := c.fetcher.Fetch(fetchCtx, job.Source)
+	log.Printf("worker %d fetching from %s", workerID, target)
+
+	// Fetch the content, retrying transient failures
+	start := time.Now()
+	content, attempts, err := c.fetchWithRetry(ctx, job.Source, target)
+
+	// The job's metadata is passed on to the parse job, so it is copied
+	// rather than written to
+	metadata := make(map[string]interface{}, len(job.Metadata)+1)
+	for key, value := range job.Metadata {
+		metadata[key] = value
+	}
+	metadata["fetch_attempts"] = attempts
 
 	// Create result
 	result := &model.FetchResult{
 		Source:      job.Source,
 		Content:     content,
 		FetchedAt:   time.Now(),
+		Duration:    time.Since(start),
 		ProcessedBy: workerID,
 		Error:       err,
+		Metadata:    metadata,
 	}
 
 	// Update stats
@@ -294,33 +433,96 @@ func (c *Coordinator) processFetchJob(ctx context.Context, job *model.FetchJob,
 	return result
 }
 
-// parseWorker processes parse jobs from the parse jobs channel
+// processSitemapJob expands the sitemap of a source and submits a fetch job
+// for every selected page. The result carries no content, so nothing is
+// parsed for the sitemap itself.
+func (c *Coordinator) processSitemapJob(ctx context.Context, job *model.FetchJob, workerID int) *model.FetchResult {
+	log.Printf("worker %d expanding sitemap %s", workerID, job.Source.URL)
+
+	start := time.Now()
+	urls, err := c.expandSitemap(ctx, job.Source)
+
+	result := &model.FetchResult{
+		Source:      job.Source,
+		FetchedAt:   time.Now(),
+		Duration:    time.Since(start),
+		ProcessedBy: workerID,
+		Error:       err,
+		Metadata:    map[string]interface{}{"sitemap_urls": len(urls)},
+	}
+
+	c.mu.Lock()
+	if err != nil {
+		c.stats.FailedFetches++
+	} else {
+		c.stats.SuccessfulFetches++
+	}
+
+	c.stats.ProcessedSources = c.stats.SuccessfulFetches + c.stats.FailedFetches
+	c.mu.Unlock()
+
+	if err != nil {
+		return result
+	}
+
+	jobs := make([]*model.FetchJob, len(urls))
+	for i, u := range urls {
+		jobs[i] = &model.FetchJob{
+			Source:      job.Source,
+			URL:         u,
+			SubmittedAt: time.Now(),
+			Metadata:    map[string]interface{}{"sitemap": job.Source.URL},
+		}
+	}
+
+	c.submitChildJobs(ctx, jobs)
+
+	return result
+}
+
+// submitChildJobs queues jobs created by a worker for one source, such as
+// its next page. They take the priority of the source.
+func (c *Coordinator) submitChildJobs(ctx context.Context, jobs []*model.FetchJob) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	c.addPending(jobs[0].Source, len(jobs))
+
+	for _, job := range jobs {
+		job.Priority = job.Source.Priority
+		c.fetchJobs.Push(ctx, job, job.Priority)
+	}
+}
+
+// parseWorker processes parse jobs from the parse queue, highest priority first
 func (c *Coordinator) parseWorker(ctx context.Context, workerID int) {
 	log.Printf("Parse worker %d started", workerID)
 
 	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("Parse worker %d stopping: context cancelled", workerID)
-			return
-		case job, ok := <-c.parseJobs:
-			if !ok {
-				log.Printf("Parse worker %d stopping: channel closed", workerID)
-				return
+		job, ok := c.parseJobs.Pop(ctx)
+		if !ok {
+			if ctx.Err() != nil {
+				log.Printf("Parse worker %d stopping: context cancelled", workerID)
+			} else {
+				log.Printf("Parse worker %d stopping: queue closed", workerID)
 			}
+			return
+		}
 
-			// Process the parse job
-			result := c.processParseJob(ctx, job, workerID)
+		// Process the parse job
+		result := c.processParseJob(ctx, job, workerID)
 
-			// Send the result
-			select {
-			case c.parseResults <- result:
-				// Result sent successfully
-			case <-ctx.Done():
-				log.Printf("Parse worker %d: context cancelled while sending result", workerID)
-				return
-			}
+		// Send the result
+		select {
+		case c.parseResults <- result:
+			// Result sent successfully
+		case <-ctx.Done():
+			log.Printf("Parse worker %d: context cancelled while sending result", workerID)
+			return
 		}
+
+		c.jobDone(job.Source)
 	}
 }
 
@@ -329,10 +531,11 @@ func (c *Coordinator) processParseJob(ctx context.Context, job *model.ParseJob,
 	source := job.Source
 	log.Printf("worker %d parsing content from %s using %s parser", workerID, source.Name, source.Parser)
 
-	// Get the appropriate parser
-	parser, exists := c.parsers[source.Parser]
-	if !exists {
-		err := fmt.Errorf("no parser available for type: %s", source.Parser)
+	// Look up the parser in the registry, so parsers registered after the
+	// coordinator was created are picked up as well
+	p, err := parser.Get(source.Parser)
+	if err != nil {
+		err = fmt.Errorf("no parser available for type: %s", source.Parser)
 
 		// Update stats
 		c.mu.Lock()
@@ -347,16 +550,39 @@ func (c *Coordinator) processParseJob(ctx context.Context, job *model.ParseJob,
 		}
 	}
 
-	// Parse the content
-	items, err := parser.Parse(ctx, job.Content, source)
+	// Parse the content; a detail page is merged into its listing item
+	var items []model.Item
+	if _, detail := job.Metadata[detailItemKey]; detail {
+		items, err = c.processDetailJob(ctx, job)
+	} else {
+		items, err = c.runParser(ctx, p, job.Content, source)
+
+		// A later page without item containers is the empty page past the
+		// end of the listing, not a failed parse
+		if index, _ := job.Metadata["page_index"].(int); index > 1 && errors.Is(err, parser.ErrNoContainers) {
+			items, err = nil, nil
+		}
+
+		// Decide whether to fetch the next page
+		if _, paginated := job.Metadata["page"]; paginated && err == nil {
+			items = c.followPages(ctx, job, items)
+		}
+
+		// Follow the items into their detail pages
+		if source.DetailEnabled() && err == nil {
+			items = c.followDetails(ctx, source, items)
+		}
+	}
 
 	// Create result
 	result := &model.ParseResult{
 		Source:      source,
 		Items:       items,
+		Results:     c.normalizer.NormalizeAll(items, job.Content),
 		ParsedAt:    time.Now(),
 		ProcessedBy: workerID,
 		Error:       err,
+		Metadata:    job.Metadata,
 	}
 
 	// Update stats
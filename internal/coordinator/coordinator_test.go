@@ -0,0 +1,386 @@
+package coordinator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// newTestSource decodes a source from YAML, with %[1]s standing for the
+// test server's URL
+func newTestSource(t *testing.T, serverURL, source string) *model.Source {
+	t.Helper()
+
+	s := &model.Source{}
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf(source, serverURL)), s); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// runSource takes a source through a fresh coordinator and returns the items
+// parsed from it and the number of failed parses
+func runSource(t *testing.T, cfg *config.Config, source *model.Source) ([]model.Item, int) {
+	t.Helper()
+
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var items []model.Item
+	failed := 0
+	var collectors sync.WaitGroup
+	collectors.Add(2)
+
+	go func() {
+		defer collectors.Done()
+		for range c.GetFetchResults() {
+		}
+	}()
+
+	go func() {
+		defer collectors.Done()
+		for result := range c.GetParseResults() {
+			if result.Error != nil {
+				failed++
+			}
+			items = append(items, result.Items...)
+		}
+	}()
+
+	c.SubmitFetchJob(source)
+	waitErr := c.Wait()
+	c.Stop()
+	collectors.Wait()
+
+	if waitErr != nil {
+		t.Fatalf("Wait() error = %v", waitErr)
+	}
+
+	return items, failed
+}
+
+// itemPages returns the titles of items with the page each came from, in
+// title order
+func itemPages(items []model.Item) []string {
+	pages := make([]string, len(items))
+	for i, item := range items {
+		pages[i] = fmt.Sprintf("%s@%v", item.Title, item.ExtraFields["page"])
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+func TestPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		switch r.URL.Path {
+		case "/numbered":
+			// The third page is past the end of the listing
+			switch query.Get("page") {
+			case "1":
+				fmt.Fprint(w, `<ul><li><a href="/a">A</a></li><li><a href="/b">B</a></li></ul>`)
+			case "2":
+				fmt.Fprint(w, `<ul><li><a href="/c">C</a></li></ul>`)
+			default:
+				fmt.Fprint(w, `<p>No more results</p>`)
+			}
+		case "/linked":
+			if query.Get("after") == "" {
+				w.Header().Set("Link", `</linked?after=b>; rel="next"`)
+				fmt.Fprint(w, `[{"id": "a", "title": "A"}, {"id": "b", "title": "B"}]`)
+			} else {
+				fmt.Fprint(w, `[{"id": "c", "title": "C"}]`)
+			}
+		case "/next":
+			if query.Get("p") == "" {
+				fmt.Fprint(w, `<ul><li><a href="/a">A</a></li></ul><a rel="next" href="/next?p=2">Next</a>`)
+			} else {
+				fmt.Fprint(w, `<ul><li><a href="/b">B</a></li></ul>`)
+			}
+		case "/cursor":
+			if query.Get("cursor") == "" {
+				fmt.Fprint(w, `{"items": [{"id": "a", "title": "A"}], "next": "c2"}`)
+			} else {
+				fmt.Fprint(w, `{"items": [{"id": "b", "title": "B"}], "next": null}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "page",
+			source: `
+name: Numbered
+url: %[1]s/numbered
+parser: html
+selectors: {container: li, title: a, url: a}
+pagination: {enabled: true, param_name: page, start_page: 1}
+`,
+			want: []string{"A@1", "B@1", "C@2"},
+		},
+		{
+			name: "link_header",
+			source: `
+name: Linked
+url: %[1]s/linked
+parser: json
+mappings: {id: id, title: title}
+pagination: {enabled: true, strategy: link_header}
+`,
+			want: []string{"A@1", "B@1", "C@2"},
+		},
+		{
+			name: "html_next",
+			source: `
+name: Next
+url: %[1]s/next
+parser: html
+selectors: {container: li, title: a, url: a}
+pagination: {enabled: true, strategy: html_next}
+`,
+			want: []string{"A@1", "B@2"},
+		},
+		{
+			name: "json_cursor",
+			source: `
+name: Cursor
+url: %[1]s/cursor
+parser: json
+mappings: {container: items, id: id, title: title}
+pagination: {enabled: true, strategy: json_cursor, cursor_path: next, cursor_param: cursor}
+`,
+			want: []string{"A@1", "B@2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, failed := runSource(t, config.DefaultConfig(), newTestSource(t, server.URL, tt.source))
+
+			if failed != 0 {
+				t.Errorf("got %d failed parses, want none", failed)
+			}
+
+			if got := itemPages(items); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got items %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandSitemap(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+
+		base := "http://" + r.Host
+		index := func(children ...string) {
+			fmt.Fprint(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+			for _, child := range children {
+				fmt.Fprintf(w, `<sitemap><loc>%s%s</loc></sitemap>`, base, child)
+			}
+			fmt.Fprint(w, `</sitemapindex>`)
+		}
+
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			// Lists itself and the posts twice, which must be fetched once
+			index("/posts.xml", "/pages.xml.gz", "/nested/1.xml", "/sitemap.xml", "/posts.xml")
+		case "/posts.xml":
+			fmt.Fprintf(w, `<urlset>
+				<url><loc>%[1]s/posts/old</loc><lastmod>2024-01-01</lastmod></url>
+				<url><loc>%[1]s/posts/undated</loc></url>
+				<url><loc>%[1]s/posts/new</loc><lastmod>2024-03-01T10:00:00Z</lastmod></url>
+				<url><loc>%[1]s/posts/mid</loc><lastmod>2024-02</lastmod></url>
+			</urlset>`, base)
+		case "/pages.xml.gz":
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			fmt.Fprintf(zw, `<urlset><url><loc>%s/about</loc></url></urlset>`, base)
+			zw.Close()
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(buf.Bytes())
+		case "/nested/1.xml":
+			index("/nested/2.xml")
+		case "/nested/2.xml":
+			index("/nested/3.xml", "/nested/3-index.xml")
+		case "/nested/3.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%s/deep</loc></url></urlset>`, base)
+		case "/nested/3-index.xml":
+			// Past maxSitemapDepth, so its children are not fetched
+			index("/nested/4.xml")
+		case "/nested/4.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%s/too-deep</loc></url></urlset>`, base)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		sitemap string
+		want    []string
+	}{
+		{
+			name:    "process all",
+			sitemap: `{enabled: true, process_all: true, pattern: /posts/}`,
+			want:    []string{"/posts/new", "/posts/mid", "/posts/old", "/posts/undated", "/about", "/deep"},
+		},
+		{
+			name:    "pattern",
+			sitemap: `{enabled: true, pattern: /posts/}`,
+			want:    []string{"/posts/new", "/posts/mid", "/posts/old", "/posts/undated"},
+		},
+		{
+			name:    "max urls",
+			sitemap: `{enabled: true, pattern: /posts/, max_urls: 2}`,
+			want:    []string{"/posts/new", "/posts/mid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			hits = make(map[string]int)
+			mu.Unlock()
+
+			c, err := New(config.DefaultConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			source := newTestSource(t, server.URL, "{name: Sitemap, url: %[1]s/sitemap.xml, sitemap: "+tt.sitemap+"}")
+
+			urls, err := c.expandSitemap(context.Background(), source)
+			if err != nil {
+				t.Fatalf("expandSitemap() error = %v", err)
+			}
+
+			want := make([]string, len(tt.want))
+			for i, path := range tt.want {
+				want[i] = server.URL + path
+			}
+
+			if fmt.Sprint(urls) != fmt.Sprint(want) {
+				t.Errorf("expandSitemap() = %v, want %v", urls, want)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if hits["/sitemap.xml"] != 1 || hits["/posts.xml"] != 1 {
+				t.Errorf("fetched the index %d and the posts %d times, want once each", hits["/sitemap.xml"], hits["/posts.xml"])
+			}
+
+			if hits["/nested/3-index.xml"] != 1 || hits["/nested/4.xml"] != 0 {
+				t.Errorf("fetched the index at the depth limit %d times and its child %d times, want 1 and 0", hits["/nested/3-index.xml"], hits["/nested/4.xml"])
+			}
+		})
+	}
+}
+
+func TestDetailPages(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/list":
+			fmt.Fprint(w, `<ul>
+				<li><a href="/posts/1">One</a></li>
+				<li><a href="/posts/2">Two</a></li>
+				<li><a href="/posts/gone">Gone</a></li>
+				<li><a href="/posts/3">Three</a></li>
+			</ul>`)
+		case "/posts/1", "/posts/2", "/posts/3":
+			fmt.Fprintf(w, `<h1>Headline</h1><div class="body">Body of %[1]s</div><span class="by">Author of %[1]s</span>`, r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := newTestSource(t, server.URL, `
+name: Listing
+url: %[1]s/list
+parser: html
+selectors: {container: li, title: a, url: a}
+detail:
+  selectors: {content: .body, author: .by}
+  max_fetches: 3
+`)
+
+	items, failed := runSource(t, config.DefaultConfig(), source)
+	if failed != 0 {
+		t.Errorf("got %d failed parses, want none", failed)
+	}
+
+	byTitle := make(map[string]model.Item)
+	for _, item := range items {
+		byTitle[item.Title] = item
+	}
+
+	if len(items) != 4 || len(byTitle) != 4 {
+		t.Fatalf("got items %v, want One, Two, Gone and Three once each", itemPages(items))
+	}
+
+	// Detail pages fill in the listing items, which keep their titles
+	for _, title := range []string{"One", "Two"} {
+		item := byTitle[title]
+		path := item.URL[len(server.URL):]
+		if item.Content != "Body of "+path || item.Author != "Author of "+path || item.ExtraFields["detail_url"] != item.URL {
+			t.Errorf("%s: content %q, author %q, detail_url %v; want the fields of %s", title, item.Content, item.Author, item.ExtraFields["detail_url"], path)
+		}
+	}
+
+	// A failed detail fetch keeps the listing item
+	if gone := byTitle["Gone"]; gone.ExtraFields["detail_error"] == nil || gone.Content != "" {
+		t.Errorf("Gone: detail_error %v, content %q; want the listing item with the error", gone.ExtraFields["detail_error"], gone.Content)
+	}
+
+	// The fourth item is past max_fetches and is emitted as listed
+	mu.Lock()
+	defer mu.Unlock()
+
+	if three := byTitle["Three"]; three.Content != "" || hits["/posts/3"] != 0 {
+		t.Errorf("Three: content %q, detail page fetched %d times; want it left unfetched", three.Content, hits["/posts/3"])
+	}
+}
@@ -0,0 +1,137 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/parser"
+)
+
+// detailItemKey is the job metadata key holding the listing item a detail
+// page belongs to
+const detailItemKey = "detail_item"
+
+// followDetails submits a fetch for the detail page of every listing item
+// with a URL, within the source's per-run cap. The returned items are the
+// ones that are not followed and can be emitted straight away; the others
+// are emitted once their detail page has been merged in.
+func (c *Coordinator) followDetails(ctx context.Context, source *model.Source, items []model.Item) []model.Item {
+	var jobs []*model.FetchJob
+	var emit []model.Item
+	capped := 0
+
+	c.mu.Lock()
+	for _, item := range items {
+		if item.URL == "" {
+			emit = append(emit, item)
+			continue
+		}
+
+		if limit := source.Detail.MaxFetches; limit > 0 && c.details[source] >= limit {
+			capped++
+			emit = append(emit, item)
+			continue
+		}
+		c.details[source]++
+
+		jobs = append(jobs, &model.FetchJob{
+			Source:      source,
+			URL:         item.URL,
+			SubmittedAt: time.Now(),
+			Metadata:    map[string]interface{}{detailItemKey: item},
+		})
+	}
+	c.mu.Unlock()
+
+	if capped > 0 {
+		log.Printf("Detail pages for %s: reached the limit of %d fetches, %d items kept without their detail page", source.Name, source.Detail.MaxFetches, capped)
+	}
+
+	c.submitChildJobs(ctx, jobs)
+
+	return emit
+}
+
+// processDetailJob merges the fields parsed from a detail page into its
+// listing item. When the page could not be fetched or parsed, the listing
+// item is kept as it is and the failure is recorded in "detail_error".
+func (c *Coordinator) processDetailJob(ctx context.Context, job *model.ParseJob) ([]model.Item, error) {
+	item := job.Metadata[detailItemKey].(model.Item)
+
+	if fetchErr, ok := job.Metadata["detail_error"].(string); ok {
+		item.ExtraFields = withField(item.ExtraFields, "detail_error", fetchErr)
+		return []model.Item{item}, nil
+	}
+
+	p, err := parser.Get("html")
+	if err != nil {
+		return nil, err
+	}
+
+	detailSource := job.Source.DetailSource()
+
+	details, err := c.runParser(ctx, p, job.Content, detailSource)
+	if err == nil && len(details) == 0 {
+		err = fmt.Errorf("no content found")
+	}
+
+	if err != nil {
+		log.Printf("Warning: Detail page %s of %s: %v", job.Content.URL, job.Source.Name, err)
+		item.ExtraFields = withField(item.ExtraFields, "detail_error", err.Error())
+		return []model.Item{item}, nil
+	}
+
+	return []model.Item{mergeDetail(item, details[0], detailSource, job.Content.URL)}, nil
+}
+
+// mergeDetail fills a listing item with the fields of its detail page. The
+// detail page wins for content, author, category, date and extra fields; the
+// listing keeps its title unless the detail selectors ask for one, and
+// always keeps its identity and URL.
+func mergeDetail(item, detail model.Item, detailSource *model.Source, detailURL string) model.Item {
+	if detail.Title != "" && (item.Title == "" || detailSource.Selector["title"] != "") {
+		item.Title = detail.Title
+	}
+
+	if detail.Content != "" {
+		item.Content = detail.Content
+	}
+
+	if detail.Author != "" {
+		item.Author = detail.Author
+	}
+
+	if detail.Category != "" {
+		item.Category = detail.Category
+	}
+
+	if !detail.Date.IsZero() {
+		item.Date = detail.Date
+		delete(item.ExtraFields, "raw_date")
+		delete(item.ExtraFields, "date_error")
+	}
+
+	for key, value := range detail.ExtraFields {
+		if key == "missing_fields" {
+			key = "detail_missing_fields"
+		}
+		item.ExtraFields = withField(item.ExtraFields, key, value)
+	}
+
+	item.ExtraFields = withField(item.ExtraFields, "detail_url", detailURL)
+
+	return item
+}
+
+// withField sets a field in a possibly nil map of extra fields
+func withField(fields map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields[key] = value
+
+	return fields
+}
@@ -0,0 +1,182 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/parser"
+)
+
+var (
+	// ErrParserPanic is wrapped by the error of a parse that panicked
+	ErrParserPanic = errors.New("parser panicked")
+
+	// ErrParserTimeout is wrapped by the error of a parse that overran parser.timeout
+	ErrParserTimeout = errors.New("parser timed out")
+)
+
+// stackSnippetFrames is the number of stack frames kept in a ParseError
+const stackSnippetFrames = 8
+
+// parseLabel is the profiler label that tags the goroutine of each guarded
+// parse, so that the stack of an abandoned parse can be found
+const parseLabel = "parse"
+
+// parseSeq numbers guarded parses for parseLabel
+var parseSeq atomic.Uint64
+
+// ParseError describes a parse that panicked or overran its deadline. Stack
+// holds the innermost frames of the parser at the time.
+type ParseError struct {
+	Parser string
+	Err    error
+	Stack  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Stack == "" {
+		return fmt.Sprintf("%s parser: %v", e.Parser, e.Err)
+	}
+
+	return fmt.Sprintf("%s parser: %v\n%s", e.Parser, e.Err, e.Stack)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseOutcome is what a guarded parse sends back to the worker
+type parseOutcome struct {
+	items []model.Item
+	err   error
+}
+
+// runParser runs a parser under parser.timeout and turns a panic into an
+// error, so a pathological document or a faulty custom parser cannot hang or
+// kill the worker. A parser that ignores the cancelled context keeps running
+// in the background after a timeout, but the worker moves on.
+func (c *Coordinator) runParser(ctx context.Context, p parser.ItemParser, content *model.Content, source *model.Source) ([]model.Item, error) {
+	timeout := c.config.Parser.Timeout
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan parseOutcome, 1)
+	id := strconv.FormatUint(parseSeq.Add(1), 10)
+
+	go func() {
+		pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(parseLabel, id)))
+
+		defer func() {
+			if r := recover(); r != nil {
+				done <- parseOutcome{err: &ParseError{
+					Parser: source.Parser,
+					Err:    fmt.Errorf("%w: %v", ErrParserPanic, r),
+					Stack:  stackSnippet(debug.Stack(), stackSnippetFrames),
+				}}
+			}
+		}()
+
+		items, err := p.Parse(ctx, content, source)
+		done <- parseOutcome{items: items, err: err}
+	}()
+
+	select {
+	case outcome := <-done:
+		return outcome.items, outcome.err
+	case <-ctx.Done():
+	}
+
+	// The parser may have finished just as the deadline passed
+	select {
+	case outcome := <-done:
+		return outcome.items, outcome.err
+	default:
+	}
+
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) || timeout <= 0 {
+		return nil, ctx.Err()
+	}
+
+	log.Printf("Warning: %s parser for %s still running after %s, abandoning it", source.Parser, source.Name, timeout)
+
+	return nil, &ParseError{
+		Parser: source.Parser,
+		Err:    fmt.Errorf("%w after %s", ErrParserTimeout, timeout),
+		Stack:  stackSnippet(labelledStack(id), stackSnippetFrames),
+	}
+}
+
+// labelledStack returns the stack of the parse goroutine with the given
+// parseLabel, read from the goroutine profile, which groups goroutines by
+// stack and labels. Frames are laid out as in a stack trace.
+func labelledStack(id string) []byte {
+	var profile bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&profile, 1); err != nil {
+		return nil
+	}
+
+	label := strconv.Quote(parseLabel) + ":" + strconv.Quote(id)
+
+	for _, record := range strings.Split(profile.String(), "\n\n") {
+		if !strings.Contains(record, "# labels: ") || !strings.Contains(record, label) {
+			continue
+		}
+
+		// Frame lines read "#  <pc>  <function>+<offset>  <file>:<line>"
+		var stack bytes.Buffer
+		for _, line := range strings.Split(record, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 4 || fields[0] != "#" {
+				continue
+			}
+
+			function, _, _ := strings.Cut(fields[2], "+0x")
+			fmt.Fprintf(&stack, "%s(...)\n\t%s\n", function, fields[3])
+		}
+
+		return stack.Bytes()
+	}
+
+	return nil
+}
+
+// stackSnippet keeps the innermost frames of a stack trace, leaving out the
+// trace header, the runtime's own frames and, for a panic, the frames of the
+// recovery itself
+func stackSnippet(stack []byte, frames int) string {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "goroutine ") {
+		lines = lines[1:]
+	}
+
+	for i := 0; i+1 < len(lines); i += 2 {
+		if strings.HasPrefix(lines[i], "panic(") {
+			lines = lines[i+2:]
+			break
+		}
+	}
+
+	var snippet []string
+	for i := 0; i+1 < len(lines) && len(snippet) < 2*frames; i += 2 {
+		fn := lines[i]
+		if strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "runtime/debug.") || strings.HasPrefix(fn, "panic(") {
+			continue
+		}
+
+		snippet = append(snippet, fn, lines[i+1])
+	}
+
+	return strings.Join(snippet, "\n")
+}
@@ -0,0 +1,64 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/parser"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
+)
+
+func explodingParse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	var items map[string]model.Item
+	items["boom"] = model.Item{}
+	return nil, nil
+}
+
+// hangingParser returns a parser that ignores its context and blocks until
+// release is closed
+func hangingParser(release chan struct{}) parser.ParserFunc {
+	return func(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+		<-release
+		return nil, nil
+	}
+}
+
+func TestRunParser(t *testing.T) {
+	c := &Coordinator{config: &config.Config{Parser: config.ParserConfig{Timeout: 50 * time.Millisecond}}}
+	source := &model.Source{Name: "test", Parser: "custom"}
+	content := &model.Content{Body: []byte("x")}
+
+	_, err := c.runParser(context.Background(), parser.ParserFunc(explodingParse), content, source)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || !errors.Is(err, ErrParserPanic) {
+		t.Fatalf("runParser() error = %v, want a parser panic", err)
+	}
+
+	if !strings.Contains(parseErr.Stack, "explodingParse") {
+		t.Errorf("runParser() stack snippet does not name the parser:\n%s", parseErr.Stack)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err = c.runParser(context.Background(), hangingParser(release), content, source)
+	if !errors.As(err, &parseErr) || !errors.Is(err, ErrParserTimeout) {
+		t.Fatalf("runParser() error = %v, want a parser timeout", err)
+	}
+
+	if !strings.Contains(parseErr.Stack, "hangingParser") {
+		t.Errorf("runParser() stack snippet does not show where the parser hangs:\n%s", parseErr.Stack)
+	}
+
+	items, err := c.runParser(context.Background(), parser.ParserFunc(func(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+		return []model.Item{{Title: "ok"}}, nil
+	}), content, source)
+	if err != nil || len(items) != 1 {
+		t.Errorf("runParser() = %v, %v after a failure, want one item", items, err)
+	}
+}
@@ -0,0 +1,137 @@
+package coordinator
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/parser"
+)
+
+// defaultMaxPages caps pagination for sources that do not set max_pages
+const defaultMaxPages = 10
+
+// pageRun tracks the pages of one source fetched during the current run
+type pageRun struct {
+	seen    map[string]bool // IDs of the items extracted so far
+	visited map[string]bool // Page URLs already submitted, to break link cycles
+}
+
+// firstPageJob creates the fetch job for the first page of a paginated
+// source and resets what was seen for the source in an earlier run. Pages
+// are numbered from start_page for the page strategy and from 1 for the
+// strategies that follow links or cursors.
+func (c *Coordinator) firstPageJob(source *model.Source) *model.FetchJob {
+	first, page := source.URL, 1
+	if source.PaginationStrategy() == model.PaginationPage {
+		page = source.Pagination.StartPage
+		first = source.GetURLWithPage(page)
+	}
+
+	c.mu.Lock()
+	c.pages[source] = &pageRun{
+		seen:    make(map[string]bool),
+		visited: map[string]bool{first: true},
+	}
+	c.mu.Unlock()
+
+	return c.pageJob(source, first, page, 1)
+}
+
+// pageJob creates the fetch job for a page; index counts pages from 1
+func (c *Coordinator) pageJob(source *model.Source, pageURL string, page, index int) *model.FetchJob {
+	return &model.FetchJob{
+		Source:      source,
+		URL:         pageURL,
+		SubmittedAt: time.Now(),
+		Metadata: map[string]interface{}{
+			"page":       page,
+			"page_index": index,
+		},
+	}
+}
+
+// followPages records page provenance on the items of a parsed page, drops
+// items already seen on earlier pages and submits the next page unless the
+// page was empty, held nothing new, had no next page, or the page cap has
+// been reached
+func (c *Coordinator) followPages(ctx context.Context, job *model.ParseJob, items []model.Item) []model.Item {
+	page, _ := job.Metadata["page"].(int)
+	index, _ := job.Metadata["page_index"].(int)
+	source := job.Source
+
+	c.mu.Lock()
+	run, ok := c.pages[source]
+	if !ok {
+		run = &pageRun{seen: make(map[string]bool), visited: make(map[string]bool)}
+		c.pages[source] = run
+	}
+
+	fresh := items[:0]
+	for i, item := range items {
+		if run.seen[item.ID] {
+			continue
+		}
+		run.seen[item.ID] = true
+
+		if item.ExtraFields == nil {
+			item.ExtraFields = make(map[string]interface{})
+		}
+		item.ExtraFields["page"] = page
+		item.ExtraFields["page_index"] = index
+		item.ExtraFields["page_position"] = i + 1
+		item.ExtraFields["page_url"] = job.Content.URL
+
+		fresh = append(fresh, item)
+	}
+	c.mu.Unlock()
+
+	maxPages := source.Pagination.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+
+	switch {
+	case len(items) == 0:
+		log.Printf("Pagination for %s stopped: page %d has no items", source.Name, page)
+		return fresh
+	case len(fresh) == 0:
+		log.Printf("Pagination for %s stopped: page %d only repeats items already seen", source.Name, page)
+		return fresh
+	case index >= maxPages:
+		log.Printf("Pagination for %s stopped: reached the limit of %d pages", source.Name, maxPages)
+		return fresh
+	}
+
+	// Work out where the next page is
+	var next string
+	if source.PaginationStrategy() == model.PaginationPage {
+		next = source.GetURLWithPage(page + 1)
+	} else {
+		var err error
+		if next, err = parser.NextPageURL(job.Content, source); err != nil {
+			log.Printf("Pagination for %s stopped: %v", source.Name, err)
+			return fresh
+		}
+	}
+
+	if next == "" {
+		log.Printf("Pagination for %s finished: page %d has no next page", source.Name, page)
+		return fresh
+	}
+
+	c.mu.Lock()
+	cycle := run.visited[next]
+	run.visited[next] = true
+	c.mu.Unlock()
+
+	if cycle {
+		log.Printf("Pagination for %s stopped: next page %s was already fetched", source.Name, next)
+		return fresh
+	}
+
+	c.submitChildJobs(ctx, []*model.FetchJob{c.pageJob(source, next, page+1, index+1)})
+
+	return fresh
+}
@@ -0,0 +1,161 @@
+package coordinator
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// jobQueue is a priority queue of jobs feeding a worker pool. Jobs with a
+// higher priority are taken first and jobs of equal priority in the order
+// they were pushed. A job gains one priority level for every aging interval
+// it waits, so a steady stream of high-priority jobs cannot starve the rest.
+//
+// With a capacity, Push blocks while the queue is full; without one the
+// queue grows as needed.
+type jobQueue[T any] struct {
+	mu       sync.Mutex
+	items    queueItems[T]
+	capacity int
+	aging    time.Duration
+	epoch    time.Time
+	seq      uint64
+	closed   bool
+
+	// changed is closed and replaced whenever a job is pushed or taken or
+	// the queue is closed, waking every blocked Push and Pop
+	changed chan struct{}
+}
+
+type queueItem[T any] struct {
+	job T
+	key float64 // Priority adjusted for the time of pushing, see push
+	seq uint64
+}
+
+type queueItems[T any] []queueItem[T]
+
+func (q queueItems[T]) Len() int { return len(q) }
+
+func (q queueItems[T]) Less(i, j int) bool {
+	if q[i].key != q[j].key {
+		return q[i].key > q[j].key
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q queueItems[T]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queueItems[T]) Push(x interface{}) { *q = append(*q, x.(queueItem[T])) }
+
+func (q *queueItems[T]) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// newJobQueue creates a queue holding at most capacity jobs, or any number
+// when capacity is zero. A zero aging interval turns aging off.
+func newJobQueue[T any](capacity int, aging time.Duration) *jobQueue[T] {
+	return &jobQueue[T]{
+		capacity: capacity,
+		aging:    aging,
+		epoch:    time.Now(),
+		changed:  make(chan struct{}),
+	}
+}
+
+// Push adds a job, waiting for room if the queue is full. It returns false
+// if the job was dropped because ctx was cancelled or the queue was closed.
+func (q *jobQueue[T]) Push(ctx context.Context, job T, priority int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.capacity > 0 && len(q.items) >= q.capacity && !q.closed {
+		if !q.wait(ctx) {
+			return false
+		}
+	}
+
+	if q.closed {
+		return false
+	}
+
+	// A job waiting one aging interval gains one level, which puts it level
+	// with a job one level higher pushed an interval later. The ordering of
+	// two queued jobs therefore never changes, and can be fixed when pushing.
+	key := float64(priority)
+	if q.aging > 0 {
+		key -= float64(time.Since(q.epoch)) / float64(q.aging)
+	}
+
+	q.seq++
+	heap.Push(&q.items, queueItem[T]{job: job, key: key, seq: q.seq})
+	q.notify()
+
+	return true
+}
+
+// Pop takes the job with the highest priority, waiting for one to be pushed
+// if the queue is empty. It returns false once ctx is cancelled or the queue
+// is closed and empty.
+func (q *jobQueue[T]) Pop(ctx context.Context) (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 {
+		if q.closed || !q.wait(ctx) {
+			var zero T
+			return zero, false
+		}
+	}
+
+	item := heap.Pop(&q.items).(queueItem[T])
+	q.notify()
+
+	return item.job, true
+}
+
+// Close wakes every waiting Push and Pop; jobs still queued can be taken,
+// but no more are accepted
+func (q *jobQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		q.notify()
+	}
+}
+
+// Len returns the number of queued jobs
+func (q *jobQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// wait releases the lock until the queue changes. It returns false if ctx
+// was cancelled first. The caller must hold the lock.
+func (q *jobQueue[T]) wait(ctx context.Context) bool {
+	changed := q.changed
+	q.mu.Unlock()
+
+	select {
+	case <-changed:
+		q.mu.Lock()
+		return true
+	case <-ctx.Done():
+		q.mu.Lock()
+		return false
+	}
+}
+
+// notify wakes everything waiting for a change. The caller must hold the lock.
+func (q *jobQueue[T]) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
@@ -0,0 +1,72 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func popAll(t *testing.T, q *jobQueue[string]) []string {
+	t.Helper()
+
+	var jobs []string
+	for q.Len() > 0 {
+		job, ok := q.Pop(context.Background())
+		if !ok {
+			t.Fatal("Pop() failed on a non-empty queue")
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+func TestJobQueue(t *testing.T) {
+	ctx := context.Background()
+
+	q := newJobQueue[string](0, 0)
+	q.Push(ctx, "low", 0)
+	q.Push(ctx, "high", 5)
+	q.Push(ctx, "low-2", 0)
+	q.Push(ctx, "mid", 1)
+
+	want := []string{"high", "mid", "low", "low-2"}
+	if got := popAll(t, q); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+
+	// A job that has waited two aging intervals beats a later job one level up
+	q = newJobQueue[string](0, 10*time.Millisecond)
+	q.Push(ctx, "old", 0)
+	time.Sleep(30 * time.Millisecond)
+	q.Push(ctx, "new", 1)
+
+	if got := popAll(t, q); got[0] != "old" {
+		t.Errorf("order with aging = %v, want old first", got)
+	}
+
+	// A full queue blocks Push until a job is taken or ctx is cancelled
+	q = newJobQueue[string](1, 0)
+	q.Push(ctx, "first", 0)
+
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if q.Push(cancelled, "dropped", 0) {
+		t.Error("Push() on a full queue succeeded")
+	}
+
+	pushed := make(chan bool)
+	go func() { pushed <- q.Push(ctx, "second", 0) }()
+	q.Pop(ctx)
+	if !<-pushed {
+		t.Error("Push() failed after room was made")
+	}
+
+	q.Close()
+	if job, ok := q.Pop(ctx); !ok || job != "second" {
+		t.Errorf("Pop() after Close = %q, %v; want the queued job", job, ok)
+	}
+	if _, ok := q.Pop(ctx); ok {
+		t.Error("Pop() on a closed, empty queue succeeded")
+	}
+}
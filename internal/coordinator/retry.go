@@ -0,0 +1,123 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/fetcher"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
+)
+
+// fetchWithRetry fetches a URL, retrying transient failures with jittered
+// exponential backoff as set by fetcher.retry_policy. Every attempt goes
+// through the fetcher, and so waits for the rate limiter, and gets its own
+// request timeout. It returns the number of attempts made.
+func (c *Coordinator) fetchWithRetry(ctx context.Context, source *model.Source, target string) (*model.Content, int, error) {
+	policy := c.config.Fetcher.RetryPolicy
+	maxRetries := policy.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = c.config.App.MaxRetries
+	}
+
+	for attempt := 1; ; attempt++ {
+		content, err := c.fetcher.FetchURL(ctx, source, target)
+
+		if err == nil || attempt > maxRetries || ctx.Err() != nil || !isRetryable(err, policy.RetryableErrors) {
+			return content, attempt, err
+		}
+
+		delay := backoffDelay(policy, attempt)
+
+		var httpErr *fetcher.HTTPError
+		if errors.As(err, &httpErr) && httpErr.RetryAfter > delay {
+			if policy.MaxDelay > 0 && httpErr.RetryAfter > policy.MaxDelay {
+				log.Printf("Not retrying %s: server asked to wait %s, longer than the maximum retry delay", target, httpErr.RetryAfter)
+				return content, attempt, err
+			}
+			delay = httpErr.RetryAfter
+		}
+
+		log.Printf("Attempt %d for %s failed: %v; retrying in %s", attempt, target, err, delay.Round(time.Millisecond))
+
+		c.mu.Lock()
+		c.stats.Retries++
+		c.mu.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, attempt, err
+		}
+	}
+}
+
+// backoffDelay returns the delay before the retry following the given
+// attempt: the initial delay grown by the backoff factor for each earlier
+// retry, capped at the maximum delay, with the upper half randomised so that
+// workers retrying the same host spread out
+func backoffDelay(policy config.RetryPolicy, attempt int) time.Duration {
+	factor := policy.BackoffFactor
+	if factor < 1 {
+		factor = 1
+	}
+
+	delay := float64(policy.InitialDelay) * math.Pow(factor, float64(attempt-1))
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		delay = float64(policy.MaxDelay)
+	}
+
+	half := time.Duration(delay / 2)
+	if half <= 0 {
+		return time.Duration(delay)
+	}
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryable reports whether a fetch error is worth another attempt:
+// timeouts, dropped connections, 429 and 5xx responses, and errors whose
+// message contains one of the configured retryable errors. A fetch rejected
+// by an open circuit is not retried.
+func isRetryable(err error, retryableErrors []string) bool {
+	if errors.Is(err, fetcher.ErrCircuitOpen) {
+		return false
+	}
+
+	var httpErr *fetcher.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, retryable := range retryableErrors {
+		if retryable != "" && strings.Contains(message, strings.ToLower(retryable)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
+)
+
+func TestFetchWithRetry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if atomic.AddInt32(&requests, 1) < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		case "/throttled":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Fetcher.RetryPolicy.InitialDelay = time.Millisecond
+	cfg.Fetcher.RetryPolicy.MaxDelay = 10 * time.Millisecond
+
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &model.Source{Name: "test", URL: server.URL}
+
+	tests := []struct {
+		path     string
+		attempts int
+		wantErr  bool
+	}{
+		{"/flaky", 3, false},
+		{"/missing", 1, true},   // 404 is not transient
+		{"/throttled", 1, true}, // Retry-After beyond the maximum delay
+	}
+
+	for _, tt := range tests {
+		content, attempts, err := c.fetchWithRetry(context.Background(), source, server.URL+tt.path)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("fetchWithRetry(%s) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+
+		if attempts != tt.attempts {
+			t.Errorf("fetchWithRetry(%s) attempts = %d, want %d", tt.path, attempts, tt.attempts)
+		}
+
+		if err == nil && string(content.Body) != "ok" {
+			t.Errorf("fetchWithRetry(%s) body = %q", tt.path, content.Body)
+		}
+	}
+
+	policy := config.RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, BackoffFactor: 2}
+	for attempt, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		delay := backoffDelay(policy, attempt+1)
+		if delay < max/2 || delay > max {
+			t.Errorf("backoffDelay(%d) = %s, want between %s and %s", attempt+1, delay, max/2, max)
+		}
+	}
+}
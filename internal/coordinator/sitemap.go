@@ -0,0 +1,190 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+const (
+	// maxSitemapDepth limits how deeply sitemap index files may nest
+	maxSitemapDepth = 3
+
+	// maxSitemapSize is the largest uncompressed sitemap accepted, as set by
+	// the sitemaps.org protocol
+	maxSitemapSize = 50 << 20
+)
+
+// sitemapEntry is a page listed in a sitemap
+type sitemapEntry struct {
+	Loc     string
+	LastMod time.Time
+	order   int // Position across all sitemaps, to keep document order on ties
+}
+
+// sitemapDocument covers both <urlset> sitemaps and <sitemapindex> files
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+type sitemapLocation struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// expandSitemap fetches the source's sitemap, following sitemap index files,
+// and returns the page URLs to crawl: filtered by Sitemap.Pattern unless
+// ProcessAll is set, most recently modified first, and capped at MaxURLs
+func (c *Coordinator) expandSitemap(ctx context.Context, source *model.Source) ([]string, error) {
+	var pattern *regexp.Regexp
+	if !source.Sitemap.ProcessAll && source.Sitemap.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(source.Sitemap.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid sitemap pattern %q: %w", source.Sitemap.Pattern, err)
+		}
+	}
+
+	var entries []sitemapEntry
+	visited := make(map[string]bool)
+
+	if err := c.collectSitemap(ctx, source, source.URL, 0, visited, &entries); err != nil {
+		return nil, err
+	}
+
+	// Drop duplicates and pages the pattern rejects
+	seen := make(map[string]bool)
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if seen[entry.Loc] || (pattern != nil && !pattern.MatchString(entry.Loc)) {
+			continue
+		}
+
+		seen[entry.Loc] = true
+		filtered = append(filtered, entry)
+	}
+
+	// Most recently modified first; pages without a lastmod keep their order at the end
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i], filtered[j]
+		if a.LastMod.Equal(b.LastMod) {
+			return a.order < b.order
+		}
+		return a.LastMod.After(b.LastMod)
+	})
+
+	if max := source.Sitemap.MaxURLs; max > 0 && len(filtered) > max {
+		filtered = filtered[:max]
+	}
+
+	urls := make([]string, len(filtered))
+	for i, entry := range filtered {
+		urls[i] = entry.Loc
+	}
+
+	log.Printf("Sitemap for %s listed %d pages, %d selected", source.Name, len(entries), len(urls))
+
+	return urls, nil
+}
+
+// collectSitemap fetches one sitemap and appends its pages to entries,
+// recursing into the sitemaps listed by an index file
+func (c *Coordinator) collectSitemap(ctx context.Context, source *model.Source, sitemapURL string, depth int, visited map[string]bool, entries *[]sitemapEntry) error {
+	if visited[sitemapURL] {
+		return nil
+	}
+	visited[sitemapURL] = true
+
+	content, err := c.fetcher.FetchURL(ctx, source, sitemapURL)
+
+	if err != nil {
+		return fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+	}
+
+	doc, err := parseSitemap(content.Body)
+	if err != nil {
+		return fmt.Errorf("sitemap %s: %w", sitemapURL, err)
+	}
+
+	for _, loc := range doc.URLs {
+		if u := strings.TrimSpace(loc.Loc); u != "" {
+			*entries = append(*entries, sitemapEntry{
+				Loc:     u,
+				LastMod: parseLastMod(loc.LastMod),
+				order:   len(*entries),
+			})
+		}
+	}
+
+	if len(doc.Sitemaps) == 0 {
+		return nil
+	}
+
+	if depth >= maxSitemapDepth {
+		log.Printf("Sitemap index %s for %s nested too deeply, skipping %d sitemaps", sitemapURL, source.Name, len(doc.Sitemaps))
+		return nil
+	}
+
+	for _, child := range doc.Sitemaps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		childURL := strings.TrimSpace(child.Loc)
+		if childURL == "" {
+			continue
+		}
+
+		// One broken child sitemap should not lose the pages of the others
+		if err := c.collectSitemap(ctx, source, childURL, depth+1, visited, entries); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// parseSitemap decodes a sitemap or sitemap index. Gzipped (.xml.gz)
+// sitemaps have already been decompressed by the fetcher.
+func parseSitemap(body []byte) (*sitemapDocument, error) {
+	if len(body) > maxSitemapSize {
+		return nil, fmt.Errorf("sitemap exceeds %d bytes", maxSitemapSize)
+	}
+
+	var doc sitemapDocument
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+		return &doc, nil
+	}
+
+	return nil, fmt.Errorf("unexpected sitemap root element <%s>", doc.XMLName.Local)
+}
+
+// parseLastMod parses a W3C datetime as used by <lastmod>, returning the
+// zero time when it is missing or malformed
+func parseLastMod(raw string) time.Time {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,350 @@
+// Package dateparse converts the timestamps found in feeds, APIs and web
+// pages into times. It understands the common absolute layouts, relative
+// phrases such as "3 hours ago", and Unix epoch numbers.
+package dateparse
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options control how a timestamp is interpreted
+type Options struct {
+	// Format is a layout tried before the built-in ones. It may be a Go
+	// reference layout ("02/01/2006 15:04"), a strftime pattern
+	// ("%d/%m/%Y %H:%M"), or "unix" / "unix_ms" for epoch numbers.
+	Format string
+
+	// Location applies to timestamps that carry no zone; UTC when nil
+	Location *time.Location
+
+	// Now anchors relative phrases; the current time when zero
+	Now time.Time
+}
+
+// layouts are the timestamp layouts commonly found in feeds and pages,
+// roughly from the most to the least specific
+var layouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04:05",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC850,
+	time.ANSIC,
+	time.UnixDate,
+	time.RubyDate,
+	"Monday, January 2, 2006 3:04 PM",
+	"Monday, January 2, 2006",
+	"Mon, Jan 2, 2006",
+	"Mon, 2 Jan 2006",
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006 15:04",
+	"January 2, 2006",
+	"January 2 2006",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"2 January 2006 15:04",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02-Jan-2006",
+	"January 2006",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"2006.01.02",
+	"20060102T150405Z0700",
+	"20060102",
+}
+
+var (
+	ordinalSuffix = regexp.MustCompile(`(?i)\b(\d{1,2})(st|nd|rd|th)\b`)
+	meridiem      = regexp.MustCompile(`(?i)(\d)\s*([ap])\.?m\b\.?`)
+	atSeparator   = regexp.MustCompile(`(?i),?\s+at\s+`)
+	epochNumber   = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+	// relativePhrase matches "3 hours ago", "an hour ago", "2d ago" and "in 5 minutes"
+	relativePhrase = regexp.MustCompile(`^(?:(in)\s+)?(\d+|an?|one)\s*([a-z]+?)\.?(?:\s+(ago))?$`)
+)
+
+// Parse converts a raw timestamp into a time. It tries the configured format,
+// then epoch numbers, relative phrases and the built-in layouts, and returns
+// an error rather than guessing when none of them match.
+func Parse(raw string, opts Options) (time.Time, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.In(loc)
+
+	if opts.Format != "" {
+		t, err := parseWithFormat(value, opts.Format, loc)
+		if err == nil {
+			return t, nil
+		}
+
+		// Values that do not follow the configured format still get the built-in layouts
+		if opts.Format == "unix" || opts.Format == "unix_ms" {
+			return time.Time{}, err
+		}
+	}
+
+	if epochNumber.MatchString(value) {
+		if t, ok := parseEpoch(value, ""); ok {
+			return t, nil
+		}
+	}
+
+	if t, ok := parseRelative(value, now); ok {
+		return t, nil
+	}
+
+	normalized := normalize(value)
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, normalized, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date %q", raw)
+}
+
+// locations caches resolved timezones, which are looked up for every item
+var locations sync.Map
+
+// LoadLocation resolves a timezone setting: an IANA name such as
+// "Europe/Berlin", "UTC", "Local", or a fixed offset such as "+05:30"
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := loadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name[0] == '+' || name[0] == '-' {
+		t, err := time.Parse("-07:00", name)
+		if err != nil {
+			if t, err = time.Parse("-0700", name); err != nil {
+				return nil, fmt.Errorf("invalid timezone offset %q", name)
+			}
+		}
+
+		_, offset := t.Zone()
+		return time.FixedZone(name, offset), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %w", name, err)
+	}
+
+	return loc, nil
+}
+
+// parseWithFormat parses a value with the source's own format
+func parseWithFormat(value, format string, loc *time.Location) (time.Time, error) {
+	switch format {
+	case "unix", "unix_ms":
+		if t, ok := parseEpoch(value, format); ok {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("date %q is not a %s timestamp", value, format)
+	}
+
+	layout := format
+	if strings.Contains(format, "%") {
+		layout = strftimeLayout(format)
+	}
+
+	return time.ParseInLocation(layout, value, loc)
+}
+
+// parseEpoch converts a Unix timestamp. Without an explicit unit the unit is
+// inferred from the magnitude: seconds, milliseconds, microseconds or
+// nanoseconds since 1970.
+func parseEpoch(value, unit string) (time.Time, bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return time.Time{}, false
+	}
+
+	abs := math.Abs(f)
+	switch {
+	case unit == "unix_ms", unit == "" && abs >= 1e11 && abs < 1e14:
+		f *= 1e6
+	case unit == "unix", unit == "" && abs >= 1e8 && abs < 1e11:
+		f *= 1e9
+	case unit == "" && abs >= 1e14 && abs < 1e17:
+		f *= 1e3
+	case unit == "" && abs >= 1e17 && abs < 1e20:
+	default:
+		// Small numbers are more likely years or counts than timestamps
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(f)).UTC(), true
+}
+
+// relativeUnits maps the unit words of relative phrases to durations; months
+// and years are handled by calendar arithmetic
+var relativeUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "wks": 7 * 24 * time.Hour,
+	"week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// parseRelative understands "just now", "today", "yesterday", "3 hours ago",
+// "an hour ago", "2d ago" and "in 5 minutes"
+func parseRelative(value string, now time.Time) (time.Time, bool) {
+	phrase := strings.Join(strings.Fields(strings.ToLower(value)), " ")
+
+	switch phrase {
+	case "now", "just now", "moments ago", "a moment ago":
+		return now, true
+	case "today":
+		return startOfDay(now), true
+	case "yesterday":
+		return startOfDay(now).AddDate(0, 0, -1), true
+	case "tomorrow":
+		return startOfDay(now).AddDate(0, 0, 1), true
+	}
+
+	m := relativePhrase.FindStringSubmatch(phrase)
+	if m == nil || (m[1] == "") == (m[4] == "") {
+		// Exactly one of "in ..." and "... ago" is required
+		return time.Time{}, false
+	}
+
+	n := 1
+	if m[2] != "a" && m[2] != "an" && m[2] != "one" {
+		var err error
+		if n, err = strconv.Atoi(m[2]); err != nil {
+			return time.Time{}, false
+		}
+	}
+
+	if m[4] == "ago" {
+		n = -n
+	}
+
+	switch m[3] {
+	case "mo", "mon", "month", "months":
+		return now.AddDate(0, n, 0), true
+	case "y", "yr", "yrs", "year", "years":
+		return now.AddDate(n, 0, 0), true
+	}
+
+	unit, ok := relativeUnits[m[3]]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return now.Add(time.Duration(n) * unit), true
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// normalize rewrites the irregularities of human-written dates that Go
+// layouts cannot express: ordinal suffixes, "at" between date and time,
+// lower-case or dotted meridiems, "Sept" and repeated whitespace
+func normalize(value string) string {
+	value = ordinalSuffix.ReplaceAllString(value, "$1")
+	value = atSeparator.ReplaceAllString(value, " ")
+	value = meridiem.ReplaceAllStringFunc(value, func(s string) string {
+		m := meridiem.FindStringSubmatch(s)
+		return m[1] + " " + strings.ToUpper(m[2]) + "M"
+	})
+	value = strings.ReplaceAll(value, "Sept ", "Sep ")
+	value = strings.ReplaceAll(value, "Sept.", "Sep")
+
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// strftimeDirectives maps strftime directives to Go layout elements
+var strftimeDirectives = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2", 'H': "15", 'I': "03",
+	'M': "04", 'S': "05", 'p': "PM", 'b': "Jan", 'h': "Jan", 'B': "January",
+	'a': "Mon", 'A': "Monday", 'z': "-0700", 'Z': "MST", 'f': "000000",
+	'j': "002", 'T': "15:04:05", 'D': "01/02/06", 'F': "2006-01-02", '%': "%",
+}
+
+// strftimeLayout converts a strftime pattern into a Go layout
+func strftimeLayout(format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		if layout, ok := strftimeDirectives[format[i]]; ok {
+			sb.WriteString(layout)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(format[i])
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+package dateparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	berlin, err := LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+		opts Options
+		want time.Time
+	}{
+		{"RFC 822", "Sun, 03 Mar 2024 09:30:00 GMT", Options{}, time.Date(2024, 3, 3, 9, 30, 0, 0, time.UTC)},
+		{"RFC 1123 numeric zone", "Sun, 3 Mar 2024 09:30:00 +0100", Options{}, time.Date(2024, 3, 3, 8, 30, 0, 0, time.UTC)},
+		{"ISO 8601", "2024-03-03T09:30:00Z", Options{}, time.Date(2024, 3, 3, 9, 30, 0, 0, time.UTC)},
+		{"ISO 8601 without colon", "2024-03-03T10:30:00+0100", Options{}, time.Date(2024, 3, 3, 9, 30, 0, 0, time.UTC)},
+		{"human date", "Mar 3, 2024", Options{}, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"ordinal and meridiem", "March 3rd, 2024 at 9:30 pm", Options{}, time.Date(2024, 3, 3, 21, 30, 0, 0, time.UTC)},
+		{"relative hours", "3 hours ago", Options{Now: now}, now.Add(-3 * time.Hour)},
+		{"relative article", "an hour ago", Options{Now: now}, now.Add(-time.Hour)},
+		{"relative short", "2d ago", Options{Now: now}, now.AddDate(0, 0, -2)},
+		{"yesterday", "Yesterday", Options{Now: now}, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"epoch seconds", "1709458200", Options{}, time.Date(2024, 3, 3, 9, 30, 0, 0, time.UTC)},
+		{"epoch milliseconds", "1709458200000", Options{}, time.Date(2024, 3, 3, 9, 30, 0, 0, time.UTC)},
+		{"source format", "03/04/2024 10:00", Options{Format: "%d/%m/%Y %H:%M"}, time.Date(2024, 4, 3, 10, 0, 0, 0, time.UTC)},
+		{"source timezone", "2024-03-03 10:30", Options{Location: berlin}, time.Date(2024, 3, 3, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.raw, tt.opts)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.raw, err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnrecognised(t *testing.T) {
+	for _, raw := range []string{"", "soon", "42", "next tuesday-ish"} {
+		if got, err := Parse(raw, Options{}); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", raw, got)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
+)
+
+// ErrCircuitOpen is wrapped by the error of a fetch rejected because the
+// circuit of its host is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitState is the state of a host's circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed lets requests through and counts consecutive failures
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen rejects requests until the cooldown has passed
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen lets a few trial requests through; a success closes
+	// the circuit and a failure opens it again
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitStatus is a snapshot of a host's circuit breaker
+type CircuitStatus struct {
+	State               CircuitState
+	ConsecutiveFailures int
+	OpenUntil           time.Time // When an open circuit lets a trial request through
+}
+
+// circuit is the breaker of one host
+type circuit struct {
+	state     CircuitState
+	failures  int
+	openUntil time.Time
+	trials    int // Trial requests in flight while half-open
+}
+
+// Breakers holds a circuit breaker per host. A host whose requests keep
+// failing is given a rest: its fetches fail fast with ErrCircuitOpen until
+// the cooldown has passed.
+type Breakers struct {
+	config   config.CircuitBreaker
+	circuits map[string]*circuit
+	mu       sync.Mutex
+	now      func() time.Time
+}
+
+// NewBreakers creates the circuit breakers described by the configuration
+func NewBreakers(cfg config.CircuitBreaker) *Breakers {
+	return &Breakers{
+		config:   cfg,
+		circuits: make(map[string]*circuit),
+		now:      time.Now,
+	}
+}
+
+// Allow reports whether a request to host may go ahead. When it may, the
+// returned function must be called with the request's error once it is done.
+// A request that ends after ctx, the context of whoever asked for it, is not
+// counted either way: running out of the caller's time says nothing about
+// the host.
+func (b *Breakers) Allow(ctx context.Context, host string) (func(error), error) {
+	if !b.config.Enabled {
+		return func(error) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{state: CircuitClosed}
+		b.circuits[host] = c
+	}
+
+	trial := false
+
+	switch c.state {
+	case CircuitOpen:
+		if b.now().Before(c.openUntil) {
+			return nil, fmt.Errorf("%w for %s until %s", ErrCircuitOpen, host, c.openUntil.Format(time.RFC3339))
+		}
+
+		c.state = CircuitHalfOpen
+		c.trials = 0
+		log.Printf("Circuit for %s half-open, trying a request", host)
+		fallthrough
+	case CircuitHalfOpen:
+		limit := b.config.HalfOpenRequests
+		if limit <= 0 {
+			limit = 1
+		}
+
+		if c.trials >= limit {
+			return nil, fmt.Errorf("%w for %s, trial request in progress", ErrCircuitOpen, host)
+		}
+
+		c.trials++
+		trial = true
+	}
+
+	return func(err error) {
+		b.record(host, c, trial, err, ctx.Err() != nil)
+	}, nil
+}
+
+// record updates a circuit with the outcome of a request; abandoned is set
+// when the request's caller gave up on it
+func (b *Breakers) record(host string, c *circuit, trial bool, err error, abandoned bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		c.trials--
+	}
+
+	// A request cut short by its caller says nothing about the host
+	if abandoned || errors.Is(err, context.Canceled) || errors.Is(err, errRateLimitWait) {
+		return
+	}
+
+	if !isHostFailure(err) {
+		if c.state != CircuitClosed {
+			log.Printf("Circuit for %s closed", host)
+		}
+		c.state = CircuitClosed
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+
+	if c.state == CircuitHalfOpen || c.failures >= b.config.FailureThreshold {
+		if c.state != CircuitOpen {
+			log.Printf("Circuit for %s open after %d consecutive failures, cooling down for %s", host, c.failures, b.config.Cooldown)
+		}
+		c.state = CircuitOpen
+		c.openUntil = b.now().Add(b.config.Cooldown)
+	}
+}
+
+// Status returns a snapshot of every host's circuit
+func (b *Breakers) Status() map[string]CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := make(map[string]CircuitStatus, len(b.circuits))
+	for host, c := range b.circuits {
+		state := c.state
+		if state == CircuitOpen && !b.now().Before(c.openUntil) {
+			state = CircuitHalfOpen
+		}
+
+		status[host] = CircuitStatus{
+			State:               state,
+			ConsecutiveFailures: c.failures,
+			OpenUntil:           c.openUntil,
+		}
+	}
+
+	return status
+}
+
+// isHostFailure reports whether a request error suggests the host is down or
+// overloaded: the request could not be completed, or the response was a 429
+// or 5xx. Other responses, such as a 404, show the host is up. Cancelled
+// requests and waits for the rate limiter are not the host's doing.
+func isHostFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, errRateLimitWait) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
@@ -0,0 +1,112 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
+)
+
+func TestBreakers(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreakers(config.CircuitBreaker{Enabled: true, FailureThreshold: 2, Cooldown: time.Minute, HalfOpenRequests: 1})
+	b.now = func() time.Time { return now }
+
+	const host = "example.com"
+	down := &url.Error{Op: "Get", URL: "https://example.com/", Err: errors.New("connection refused")}
+
+	request := func(err error) error {
+		done, allowErr := b.Allow(context.Background(), host)
+		if allowErr != nil {
+			return allowErr
+		}
+		done(err)
+		return nil
+	}
+
+	state := func() CircuitState {
+		return b.Status()[host].State
+	}
+
+	// A 404 shows the host is up and does not count
+	request(down)
+	request(&HTTPError{StatusCode: 404, Status: "404 Not Found"})
+	request(down)
+	if state() != CircuitClosed {
+		t.Fatalf("state after non-consecutive failures = %s, want closed", state())
+	}
+
+	request(down)
+	if state() != CircuitOpen {
+		t.Fatalf("state after %d consecutive failures = %s, want open", 2, state())
+	}
+
+	if err := request(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("request to open circuit error = %v, want ErrCircuitOpen", err)
+	}
+
+	// After the cooldown a single trial goes through; a failed trial reopens
+	now = now.Add(time.Minute)
+	done, err := b.Allow(context.Background(), host)
+	if err != nil {
+		t.Fatalf("trial request error = %v", err)
+	}
+	if _, err := b.Allow(context.Background(), host); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second request while half-open error = %v, want ErrCircuitOpen", err)
+	}
+	done(&HTTPError{StatusCode: 503, Status: "503 Service Unavailable"})
+	if state() != CircuitOpen {
+		t.Fatalf("state after failed trial = %s, want open", state())
+	}
+
+	// A successful trial closes the circuit
+	now = now.Add(time.Minute)
+	if err := request(nil); err != nil {
+		t.Fatalf("trial request error = %v", err)
+	}
+	if status := b.Status()[host]; status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("status after successful trial = %+v, want closed with no failures", status)
+	}
+}
+
+func TestBreakersIgnoreRateLimitWaits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Fetcher.CircuitBreaker = config.CircuitBreaker{Enabled: true, FailureThreshold: 2, Cooldown: time.Minute, HalfOpenRequests: 1}
+	cfg.Sources.Sources = []config.Source{{URL: server.URL, RateLimit: &config.RateLimit{RequestsPerMinute: 2}}}
+
+	f, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two fetches get a token; the rest time out waiting for the limiter
+	source := &model.Source{Name: "Slow", URL: server.URL, Timeout: 300 * time.Millisecond}
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.FetchURL(context.Background(), source, server.URL)
+		}()
+	}
+	wg.Wait()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if status := f.CircuitStatus()[host]; status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("status after rate limit timeouts = %+v, want closed with no failures", status)
+	}
+}
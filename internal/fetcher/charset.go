@@ -0,0 +1,256 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// charsetSniffLen is how much of the body is searched for an XML declaration
+// or <meta> charset, following the HTML specification's prescan
+const charsetSniffLen = 1024
+
+var (
+	xmlDeclEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+	metaCharset     = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([A-Za-z0-9._:-]+)`)
+)
+
+// charsetAliases maps the labels found in the wild to the charsets we decode
+var charsetAliases = map[string]string{
+	"utf-8": "utf-8", "utf8": "utf-8", "unicode-1-1-utf-8": "utf-8",
+	"us-ascii": "utf-8", "ascii": "utf-8",
+
+	"iso-8859-1": "iso-8859-1", "iso8859-1": "iso-8859-1", "iso_8859-1": "iso-8859-1",
+	"latin1": "iso-8859-1", "latin-1": "iso-8859-1", "l1": "iso-8859-1", "cp819": "iso-8859-1",
+
+	"iso-8859-15": "iso-8859-15", "iso8859-15": "iso-8859-15", "iso_8859-15": "iso-8859-15",
+	"latin9": "iso-8859-15", "latin-9": "iso-8859-15", "l9": "iso-8859-15",
+
+	"windows-1252": "windows-1252", "cp1252": "windows-1252", "x-cp1252": "windows-1252",
+
+	"shift_jis": "shift_jis", "shift-jis": "shift_jis", "sjis": "shift_jis", "x-sjis": "shift_jis",
+	"ms_kanji": "shift_jis", "csshiftjis": "shift_jis", "windows-31j": "shift_jis",
+	"cp932": "shift_jis", "ms932": "shift_jis",
+
+	"utf-16": "utf-16le", "utf-16le": "utf-16le", "utf-16be": "utf-16be",
+}
+
+// detectCharset resolves the charset of a response body from, in order of
+// precedence, a byte order mark, the Content-Type header, the XML
+// declaration and a <meta> tag. Bodies without any declaration are treated
+// as UTF-8 when they are valid UTF-8 and as Windows-1252 otherwise.
+func detectCharset(contentType string, body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte("\xff\xfe")):
+		return "utf-16le"
+	case bytes.HasPrefix(body, []byte("\xfe\xff")):
+		return "utf-16be"
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if label := params["charset"]; label != "" {
+			return normalizeCharset(label)
+		}
+	}
+
+	head := body
+	if len(head) > charsetSniffLen {
+		head = head[:charsetSniffLen]
+	}
+
+	if m := xmlDeclEncoding.FindSubmatch(head); m != nil {
+		return normalizeCharset(string(m[1]))
+	}
+
+	if m := metaCharset.FindSubmatch(head); m != nil {
+		return normalizeCharset(string(m[1]))
+	}
+
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+
+	return "windows-1252"
+}
+
+// normalizeCharset returns the canonical name for a charset label, or the
+// lower-cased label when the charset is unknown
+func normalizeCharset(label string) string {
+	label = strings.ToLower(strings.Trim(strings.TrimSpace(label), `"'`))
+	if name, ok := charsetAliases[label]; ok {
+		return name
+	}
+
+	return label
+}
+
+// toUTF8 converts a body in the given charset to UTF-8, removing any byte
+// order mark
+func toUTF8(body []byte, charset string) ([]byte, error) {
+	switch charset {
+	case "utf-8":
+		return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), nil
+	case "iso-8859-1", "windows-1252":
+		// Documents labelled ISO-8859-1 are routinely Windows-1252, which only
+		// differs in assigning punctuation to the C1 control range
+		return decodeSingleByte(body, &windows1252High), nil
+	case "iso-8859-15":
+		return decodeSingleByte(body, &iso885915High), nil
+	case "shift_jis":
+		return decodeShiftJIS(body), nil
+	case "utf-16le", "utf-16be":
+		return decodeUTF16(body, charset == "utf-16be"), nil
+	}
+
+	return body, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// decodeSingleByte decodes a charset that matches ASCII below 0x80, using
+// high for the bytes 0x80-0xFF
+func decodeSingleByte(body []byte, high *[128]rune) []byte {
+	out := make([]byte, 0, len(body)+len(body)/4)
+	for _, b := range body {
+		if b < 0x80 {
+			out = append(out, b)
+			continue
+		}
+		out = utf8.AppendRune(out, high[b-0x80])
+	}
+
+	return out
+}
+
+// decodeShiftJIS decodes Shift_JIS, including the Windows-31J extensions
+func decodeShiftJIS(body []byte) []byte {
+	out := make([]byte, 0, len(body)*3/2)
+
+	for i := 0; i < len(body); i++ {
+		b := body[i]
+
+		switch {
+		case b < 0x80:
+			out = append(out, b)
+		case b >= 0xA1 && b <= 0xDF:
+			// Half-width katakana
+			out = utf8.AppendRune(out, rune(0xFF61+int(b)-0xA1))
+		case (b >= 0x81 && b <= 0x9F || b >= 0xE0 && b <= 0xFC) && i+1 < len(body):
+			r, ok := shiftJISRune(b, body[i+1])
+			if !ok {
+				out = utf8.AppendRune(out, utf8.RuneError)
+				continue
+			}
+			out = utf8.AppendRune(out, r)
+			i++
+		default:
+			out = utf8.AppendRune(out, utf8.RuneError)
+		}
+	}
+
+	return out
+}
+
+// shiftJISTrailCount is the number of valid trail bytes per lead byte
+const shiftJISTrailCount = 188
+
+// shiftJISRunes is shiftJISTable decoded for indexing
+var shiftJISRunes = []rune(shiftJISTable)
+
+// shiftJISRune looks up a two-byte Shift_JIS code
+func shiftJISRune(lead, trail byte) (rune, bool) {
+	var row int
+	switch {
+	case lead >= 0x81 && lead <= 0x9F:
+		row = int(lead) - 0x81
+	case lead >= 0xE0 && lead <= 0xFC:
+		row = int(lead) - 0xE0 + 0x1F
+	default:
+		return 0, false
+	}
+
+	var col int
+	switch {
+	case trail >= 0x40 && trail <= 0x7E:
+		col = int(trail) - 0x40
+	case trail >= 0x80 && trail <= 0xFC:
+		col = int(trail) - 0x80 + 0x3F
+	default:
+		return 0, false
+	}
+
+	return shiftJISRunes[row*shiftJISTrailCount+col], true
+}
+
+// decodeUTF16 decodes UTF-16 in the given byte order
+func decodeUTF16(body []byte, bigEndian bool) []byte {
+	if len(body) >= 2 && (body[0] == 0xFF && body[1] == 0xFE || body[0] == 0xFE && body[1] == 0xFF) {
+		body = body[2:]
+	}
+
+	units := make([]uint16, len(body)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(body[2*i])<<8 | uint16(body[2*i+1])
+		} else {
+			units[i] = uint16(body[2*i+1])<<8 | uint16(body[2*i])
+		}
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
+
+// windows1252High maps the bytes 0x80-0xFF of Windows-1252. The five
+// unassigned bytes map to the C1 controls, as browsers do.
+var windows1252High = [128]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+	0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7, 0xA8, 0xA9, 0xAA, 0xAB, 0xAC, 0xAD, 0xAE, 0xAF,
+	0xB0, 0xB1, 0xB2, 0xB3, 0xB4, 0xB5, 0xB6, 0xB7, 0xB8, 0xB9, 0xBA, 0xBB, 0xBC, 0xBD, 0xBE, 0xBF,
+	0xC0, 0xC1, 0xC2, 0xC3, 0xC4, 0xC5, 0xC6, 0xC7, 0xC8, 0xC9, 0xCA, 0xCB, 0xCC, 0xCD, 0xCE, 0xCF,
+	0xD0, 0xD1, 0xD2, 0xD3, 0xD4, 0xD5, 0xD6, 0xD7, 0xD8, 0xD9, 0xDA, 0xDB, 0xDC, 0xDD, 0xDE, 0xDF,
+	0xE0, 0xE1, 0xE2, 0xE3, 0xE4, 0xE5, 0xE6, 0xE7, 0xE8, 0xE9, 0xEA, 0xEB, 0xEC, 0xED, 0xEE, 0xEF,
+	0xF0, 0xF1, 0xF2, 0xF3, 0xF4, 0xF5, 0xF6, 0xF7, 0xF8, 0xF9, 0xFA, 0xFB, 0xFC, 0xFD, 0xFE, 0xFF,
+}
+
+// iso885915High maps the bytes 0x80-0xFF of ISO-8859-15, which replaces
+// eight ISO-8859-1 characters, among them the euro sign
+var iso885915High = func() [128]rune {
+	var high [128]rune
+	for i := range high {
+		high[i] = rune(0x80 + i)
+	}
+
+	for b, r := range map[byte]rune{
+		0xA4: '€', 0xA6: 'Š', 0xA8: 'š', 0xB4: 'Ž',
+		0xB8: 'ž', 0xBC: 'Œ', 0xBD: 'œ', 0xBE: 'Ÿ',
+	} {
+		high[b-0x80] = r
+	}
+
+	return high
+}()
+
+// isTextual reports whether a response should be transcoded, judging by its
+// media type and, for unlabelled responses, by its first bytes
+func isTextual(mediaType string, body []byte) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "xml"),
+		strings.HasSuffix(mediaType, "json"),
+		strings.Contains(mediaType, "javascript"),
+		strings.HasSuffix(mediaType, "csv"):
+		return true
+	case mediaType == "" || mediaType == "application/octet-stream":
+		// Compressed or other binary payloads must be left alone
+		return !(len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b) && !bytes.ContainsRune(body[:min(len(body), 512)], 0)
+	}
+
+	return false
+}
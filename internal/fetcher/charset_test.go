@@ -0,0 +1,58 @@
+package fetcher
+
+import "testing"
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"header", "text/html; charset=ISO-8859-1", "<html></html>", "iso-8859-1"},
+		{"header alias", "text/html; charset=\"x-sjis\"", "", "shift_jis"},
+		{"bom wins", "text/html; charset=iso-8859-1", "\xef\xbb\xbf<html>", "utf-8"},
+		{"xml declaration", "application/xml", `<?xml version="1.0" encoding="Shift_JIS"?><rss/>`, "shift_jis"},
+		{"meta charset", "text/html", `<html><head><meta charset="windows-1252">`, "windows-1252"},
+		{"meta http-equiv", "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=cp932">`, "shift_jis"},
+		{"valid utf-8", "text/html", "<p>caf\xc3\xa9</p>", "utf-8"},
+		{"undeclared legacy", "text/html", "<p>caf\xe9</p>", "windows-1252"},
+	}
+
+	for _, tt := range tests {
+		if got := detectCharset(tt.contentType, []byte(tt.body)); got != tt.want {
+			t.Errorf("%s: detectCharset() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		charset string
+		body    string
+		want    string
+	}{
+		{"iso-8859-1", "caf\xe9", "café"},
+		{"windows-1252", "\x93quoted\x94 \x80", "“quoted” €"},
+		{"iso-8859-15", "\xa4", "€"},
+		{"shift_jis", "\x93\xfa\x96\x7b\x8c\xea", "日本語"},
+		{"shift_jis", "\x82\xa0 \xb1", "あ ｱ"},
+		{"shift_jis", "\x87\x40", "①"},
+		{"utf-16le", "\xff\xfeh\x00i\x00", "hi"},
+	}
+
+	for _, tt := range tests {
+		got, err := toUTF8([]byte(tt.body), tt.charset)
+		if err != nil {
+			t.Fatalf("toUTF8(%q) error = %v", tt.charset, err)
+		}
+
+		if string(got) != tt.want {
+			t.Errorf("toUTF8(%q) = %q, want %q", tt.charset, got, tt.want)
+		}
+	}
+
+	if _, err := toUTF8([]byte("x"), "koi8-r"); err == nil {
+		t.Error("toUTF8() with an unsupported charset returned no error")
+	}
+}
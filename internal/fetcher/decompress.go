@@ -0,0 +1,123 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is advertised on every request. Setting it ourselves turns
+// off the transport's transparent gzip handling, so responses are always
+// decoded by readBody, whether or not a source overrides the header.
+const acceptEncoding = "gzip, deflate"
+
+// defaultMaxBodySize applies when app.http.max_body_size is not set
+const defaultMaxBodySize = 50 << 20
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// maxBodySize returns the ceiling on a decompressed response body in bytes
+func (f *Fetcher) maxBodySize() int64 {
+	if mb := f.config.App.HTTP.MaxBodySize; mb > 0 {
+		return int64(mb) << 20
+	}
+
+	return defaultMaxBodySize
+}
+
+// readBody reads a response body, undoing its Content-Encoding and
+// decompressing gzipped resources such as sitemap.xml.gz. Bodies larger than
+// limit once decompressed are rejected rather than truncated.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	var reader io.Reader = resp.Body
+
+	// Encodings are listed in the order they were applied
+	encodings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+
+		switch encoding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(reader)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode gzip body: %w", err)
+			}
+			defer zr.Close()
+			reader = zr
+		case "deflate":
+			zr, err := newDeflateReader(reader)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode deflate body: %w", err)
+			}
+			defer zr.Close()
+			reader = zr
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+		}
+
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+	}
+
+	body, err := readLimited(reader, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resources stored gzipped arrive with a gzip media type rather than a
+	// Content-Encoding; nothing downstream can use the compressed bytes
+	if bytes.HasPrefix(body, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip resource: %w", err)
+		}
+		defer zr.Close()
+
+		if body, err = readLimited(zr, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+// readLimited reads all of r, failing once more than limit bytes are read
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("response body exceeds %d bytes", limit)
+	}
+
+	return body, nil
+}
+
+// newDeflateReader decodes "deflate" bodies, which are meant to be zlib
+// streams but are raw DEFLATE data on some servers
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	// A zlib header is a multiple of 31 when read as a big-endian number
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
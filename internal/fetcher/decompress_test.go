@@ -0,0 +1,94 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	return buf.Bytes()
+}
+
+func response(encoding string, body []byte) *http.Response {
+	header := http.Header{}
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+
+	return &http.Response{Header: header, Body: io.NopCloser(bytes.NewReader(body))}
+}
+
+func TestReadBody(t *testing.T) {
+	plain := []byte("<rss><channel><title>Feed</title></channel></rss>")
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"identity", "", plain},
+		{"gzip", "gzip", compress(t, "gzip", plain)},
+		{"zlib deflate", "deflate", compress(t, "zlib", plain)},
+		{"raw deflate", "deflate", compress(t, "flate", plain)},
+		{"gzip resource", "", compress(t, "gzip", plain)},
+		{"stacked", "deflate, gzip", compress(t, "gzip", compress(t, "zlib", plain))},
+	}
+
+	for _, tt := range tests {
+		resp := response(tt.encoding, tt.body)
+
+		got, err := readBody(resp, 1<<20)
+		if err != nil {
+			t.Fatalf("%s: readBody() error = %v", tt.name, err)
+		}
+
+		if !bytes.Equal(got, plain) {
+			t.Errorf("%s: readBody() = %q, want %q", tt.name, got, plain)
+		}
+
+		if resp.Header.Get("Content-Encoding") != "" {
+			t.Errorf("%s: Content-Encoding header not removed", tt.name)
+		}
+	}
+}
+
+func TestReadBodyLimit(t *testing.T) {
+	bomb := compress(t, "gzip", []byte(strings.Repeat("a", 1<<20)))
+
+	if _, err := readBody(response("gzip", bomb), 1<<10); err == nil {
+		t.Error("readBody() accepted a body over the limit")
+	}
+
+	if _, err := readBody(response("", bomb), 1<<10); err == nil {
+		t.Error("readBody() accepted a gzip resource over the limit")
+	}
+
+	if _, err := readBody(response("br", []byte("x")), 1<<10); err == nil {
+		t.Error("readBody() accepted an unsupported encoding")
+	}
+}
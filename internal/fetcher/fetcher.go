@@ -2,11 +2,17 @@ package fetcher
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,20 +21,70 @@ import (
 	"github.com/temoto/robotstxt"
 )
 
+// HTTPError is returned for a response with a status outside the 2xx range
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // Delay asked for by a Retry-After header, zero if none
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP error: %s", e.Status)
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as
+// an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
+// errRateLimitWait is wrapped by the error of a fetch that gave up waiting
+// for the rate limiter
+var errRateLimitWait = errors.New("rate limiting error")
+
 // Fetcher is responsible for fetching content from web sources
 type Fetcher struct {
 	client      *http.Client
 	config      *config.Config
 	rateLimiter *RateLimiter
+	breakers    *Breakers
 	robotsCache map[string]*robotstxt.RobotsData
 	robotsMu    sync.RWMutex
 }
 
 // New creates a new Fetcher with the provided configuration
 func New(cfg *config.Config) (*Fetcher, error) {
+	// Bound the time to connect; the time for a whole request is bounded
+	// per request, as sources can override it
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.App.Timeouts.Connection > 0 {
+		dialer := &net.Dialer{
+			Timeout:   cfg.App.Timeouts.Connection,
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = cfg.App.Timeouts.Connection
+	}
+
 	// Create HTTP client with configured timeouts
 	client := &http.Client{
-		Timeout: time.Duration(cfg.App.Timeouts.Request),
+		Transport: transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= cfg.App.HTTP.MaxRedirects {
 				return fmt.Errorf("stopped after %d redirects", cfg.App.HTTP.MaxRedirects)
@@ -44,19 +100,60 @@ func New(cfg *config.Config) (*Fetcher, error) {
 		client:      client,
 		config:      cfg,
 		rateLimiter: limiter,
+		breakers:    NewBreakers(cfg.Fetcher.CircuitBreaker),
 		robotsCache: make(map[string]*robotstxt.RobotsData),
 	}, nil
 }
 
 // Fetch retrieves content from the specified source
 func (f *Fetcher) Fetch(ctx context.Context, source *model.Source) (*model.Content, error) {
+	return f.FetchURL(ctx, source, source.URL)
+}
+
+// FetchURL retrieves a URL on behalf of a source, such as a page listed in the
+// source's sitemap, applying the source's headers, rate limit and robots.txt
+// settings. The fetch, including any wait for the rate limiter, is bounded by
+// the source's timeout or else app.timeouts.request. While the circuit of the
+// URL's host is open it fails with an error wrapping ErrCircuitOpen instead of
+// sending the request.
+func (f *Fetcher) FetchURL(ctx context.Context, source *model.Source, rawURL string) (*model.Content, error) {
 	// Parse URL
-	parsedURL, err := url.Parse(source.URL)
+	parsedURL, err := url.Parse(rawURL)
 
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL '%s': %w", source.URL, err)
+		return nil, fmt.Errorf("invalid URL '%s': %w", rawURL, err)
+	}
+
+	caller := ctx
+	if timeout := f.requestTimeout(source); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return f.fetch(ctx, caller, source, rawURL, parsedURL)
+}
+
+// requestTimeout returns the time allowed for one fetch from a source
+func (f *Fetcher) requestTimeout(source *model.Source) time.Duration {
+	if source.Timeout > 0 {
+		return source.Timeout
 	}
 
+	return f.config.App.Timeouts.Request
+}
+
+// CircuitStatus returns the state of the circuit breaker of every host
+// fetched so far
+func (f *Fetcher) CircuitStatus() map[string]CircuitStatus {
+	return f.breakers.Status()
+}
+
+// fetch waits for robots.txt and the rate limiter, then performs the request
+// for FetchURL. Only the request itself counts towards the host's circuit
+// breaker; caller is the context FetchURL was given, before the request
+// timeout was applied.
+func (f *Fetcher) fetch(ctx, caller context.Context, source *model.Source, rawURL string, parsedURL *url.URL) (*model.Content, error) {
 	// Check robots.txt if configured
 	if source.RateLimit.RespectRobotsTxt {
 		allowed, err := f.checkRobotsTxt(ctx, parsedURL, source)
@@ -65,19 +162,33 @@ func (f *Fetcher) Fetch(ctx context.Context, source *model.Source) (*model.Conte
 			log.Printf("Warning: Error checking robots.txt for %s: %v", parsedURL.Host, err)
 			// Continue anyway since it's just a warning
 		} else if !allowed {
-			return nil, fmt.Errorf("URL '%s' disallowed by robots.txt", source.URL)
+			return nil, fmt.Errorf("URL '%s' disallowed by robots.txt", rawURL)
 		}
 	}
 
 	// Apply rate limiting
-	err = f.rateLimiter.Wait(ctx, parsedURL.Host)
+	err := f.rateLimiter.Wait(ctx, parsedURL.Host)
 
 	if err != nil {
-		return nil, fmt.Errorf("rate limiting error: %w", err)
+		return nil, fmt.Errorf("%w: %w", errRateLimitWait, err)
 	}
 
+	done, err := f.breakers.Allow(caller, parsedURL.Host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := f.get(ctx, source, rawURL)
+	done(err)
+
+	return content, err
+}
+
+// get sends the request for a URL and reads the response
+func (f *Fetcher) get(ctx context.Context, source *model.Source, rawURL string) (*model.Content, error) {
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -85,13 +196,14 @@ func (f *Fetcher) Fetch(ctx context.Context, source *model.Source) (*model.Conte
 
 	// Set headers
 	req.Header.Set("User-Agent", f.config.App.HTTP.UserAgent)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
 
 	for key, value := range source.Headers {
 		req.Header.Set(key, value)
 	}
 
 	// Execute request
-	log.Printf("Fetching %s", source.URL)
+	log.Printf("Fetching %s", rawURL)
 
 	resp, err := f.client.Do(req)
 
@@ -103,27 +215,60 @@ func (f *Fetcher) Fetch(ctx context.Context, source *model.Source) (*model.Conte
 
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("HTTP error: %s", resp.Status)
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp, f.maxBodySize())
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
+	// Hand the parsers UTF-8 whatever the site's encoding
+	contentType := resp.Header.Get("Content-Type")
+	charset := ""
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if isTextual(mediaType, body) {
+		charset = detectCharset(contentType, body)
+
+		if decoded, err := toUTF8(body, charset); err != nil {
+			log.Printf("Warning: Leaving body of %s undecoded: %v", rawURL, err)
+		} else {
+			body = decoded
+		}
+	}
+
+	fetchedAt := time.Now()
+
 	return &model.Content{
+		ID:          contentID(rawURL, fetchedAt),
 		Source:      source,
-		URL:         source.URL,
+		URL:         rawURL,
 		Body:        body,
-		ContentType: resp.Header.Get("Content-Type"),
+		ContentType: contentType,
+		Charset:     charset,
 		StatusCode:  resp.StatusCode,
 		Headers:     resp.Header,
-		FetchedAt:   time.Now(),
+		FetchedAt:   fetchedAt,
 	}, nil
 }
 
+// contentID identifies a fetch of a URL; the same URL fetched again gets a new ID
+func contentID(rawURL string, fetchedAt time.Time) string {
+	h := sha1.New()
+	h.Write([]byte(rawURL))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(fetchedAt.UnixNano(), 10)))
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
 // checkRobotsTxt verifies if the URL is allowed by the site's robots.txt
 func (f *Fetcher) checkRobotsTxt(ctx context.Context, parsedURL *url.URL, source *model.Source) (bool, error) {
 	host := parsedURL.Host
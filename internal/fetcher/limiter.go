@@ -112,7 +112,7 @@ func (rl *RateLimiter) getDomainRateLimit(domain string) int {
 			continue
 		}
 
-		if sourceURL.Host == domain && source.RateLimit.RequestsPerMinute > 0 {
+		if sourceURL.Host == domain && source.RateLimit != nil && source.RateLimit.RequestsPerMinute > 0 {
 			return source.RateLimit.RequestsPerMinute
 		}
 	}
@@ -11,12 +11,16 @@ type Content struct {
 	// Source information
 	Source *Source
 
+	// Identifies this fetch; result items refer to it through ContentID
+	ID string
+
 	// Request information
 	URL string
 
 	// Response information
 	Body        []byte
 	ContentType string
+	Charset     string // Character set a text body was decoded from to UTF-8; empty for binary bodies
 	StatusCode  int
 	Headers     http.Header
 
@@ -39,6 +43,7 @@ type Item struct {
 	ExtraFields map[string]interface{}
 
 	// Source information
+	SourceID   string
 	SourceName string
 	SourceURL  string
 
@@ -51,6 +56,8 @@ type Item struct {
 // FetchJob represents a job for the fetcher worker pool
 type FetchJob struct {
 	Source      *Source
+	URL         string // URL to fetch instead of Source.URL, e.g. a page listed in a sitemap
+	Priority    int    // Queue priority, taken from the source; higher is fetched first
 	SubmittedAt time.Time
 	Metadata    map[string]interface{} // Optional metadata
 }
@@ -60,7 +67,8 @@ type FetchResult struct {
 	Source      *Source
 	Content     *Content
 	FetchedAt   time.Time
-	ProcessedBy int // Worker ID
+	Duration    time.Duration // Time spent fetching, including rate-limit waits
+	ProcessedBy int           // Worker ID
 	Error       error
 	Metadata    map[string]interface{} // Optional metadata
 }
@@ -69,6 +77,7 @@ type FetchResult struct {
 type ParseJob struct {
 	Source      *Source
 	Content     *Content
+	Priority    int // Queue priority, carried over from the fetch job
 	SubmittedAt time.Time
 	Metadata    map[string]interface{} // Optional metadata
 }
@@ -77,6 +86,7 @@ type ParseJob struct {
 type ParseResult struct {
 	Source      *Source
 	Items       []Item
+	Results     []ResultItem // Items converted for output
 	ParsedAt    time.Time
 	ProcessedBy int // Worker ID
 	Error       error
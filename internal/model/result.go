@@ -22,14 +22,14 @@ const (
 // ResultItem represents a single piece of extracted and normalized data
 type ResultItem struct {
 	ID          string                 `json:"id"`
-	SourceID    string                 `json:"source_id"`   // ID of the source
-	ContentID   string                 `json:"content_id"`  // ID of the content it was extracted from
-	URL         string                 `json:"url"`         // Original URL
-	Title       string                 `json:"title"`       // Title of the content
-	Description string                 `json:"description"` // Short description or summary
-	Content     string                 `json:"content"`     // Full content text if available
-	Type        ResultType             `json:"type"`        // Type of result
-	Timestamp   time.Time              `json:"timestamp"`   // Publication time if available
+	SourceID    string                 `json:"source_id"`          // ID of the source
+	ContentID   string                 `json:"content_id"`         // ID of the content it was extracted from
+	URL         string                 `json:"url"`                // Original URL
+	Title       string                 `json:"title"`              // Title of the content
+	Description string                 `json:"description"`        // Short description or summary
+	Content     string                 `json:"content"`            // Full content text if available
+	Type        ResultType             `json:"type"`               // Type of result
+	Timestamp   time.Time              `json:"timestamp,omitzero"` // Publication time if known
 	Author      string                 `json:"author,omitempty"`
 	Categories  []string               `json:"categories,omitempty"`
 	Tags        []string               `json:"tags,omitempty"`
@@ -39,18 +39,16 @@ type ResultItem struct {
 	CreatedAt   time.Time              `json:"created_at"`         // When this result was created
 }
 
-// NewResultItem creates a new ResultItem with the current timestamp
+// NewResultItem creates a new ResultItem. The publication timestamp stays
+// zero until SetTimestamp is called with a known time.
 func NewResultItem(sourceID, contentID, url string) *ResultItem {
-	now := time.Now()
-
 	return &ResultItem{
 		ID:        generateID(),
 		SourceID:  sourceID,
 		ContentID: contentID,
 		URL:       url,
 		Type:      ResultTypeOther,
-		Timestamp: now,
-		CreatedAt: now,
+		CreatedAt: time.Now(),
 	}
 }
 
@@ -78,13 +76,10 @@ func (r *ResultItem) SetType(resultType ResultType) {
 	}
 }
 
-// SetTimestamp sets the publication timestamp of the result
+// SetTimestamp sets the publication timestamp of the result. A zero time
+// means the publication time is unknown and is kept as such.
 func (r *ResultItem) SetTimestamp(timestamp time.Time) {
-	if !timestamp.IsZero() {
-		r.Timestamp = timestamp
-	} else {
-		r.Timestamp = time.Now()
-	}
+	r.Timestamp = timestamp
 }
 
 // SetAuthor sets the author of the result
@@ -146,6 +141,7 @@ type AggregatedResults struct {
 	FailedCount      int          `json:"failed_count"`        // Number of failed fetches
 	TotalFetchTimeMs int64        `json:"total_fetch_time_ms"` // Total time spent fetching
 	CreatedAt        time.Time    `json:"created_at"`          // When aggregation was completed
+	Incomplete       bool         `json:"incomplete"`          // The run was cut short, e.g. by app.timeouts.total
 }
 
 // NewAggregatedResults creates a new aggregated results container
@@ -3,17 +3,28 @@ package model
 import (
 	"fmt"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/dateparse"
 )
 
 // Source represents a web source configuration to fetch content from
 type Source struct {
 	// Basic information
+	ID      string `yaml:"id"`      // Identifier of the source in the configuration
 	Name    string `yaml:"name"`    // Name of the source
 	URL     string `yaml:"url"`     // URL of the source
 	Enabled bool   `yaml:"enabled"` // Whether the source is enabled
 
+	// Scheduling priority; sources with a higher number are fetched and parsed first
+	Priority int `yaml:"priority"`
+
+	// Run schedule in daemon mode: a five-field cron expression, @hourly and the like, or "@every 15m"
+	Schedule string `yaml:"schedule"`
+
 	// Rate limiting settings
 	RateLimit struct {
 		RequestsPerMinute int  `yaml:"requests_per_minute"` // Maximun requests per minute
@@ -21,10 +32,19 @@ type Source struct {
 	} `yaml:"rate_limit"`
 
 	// Parser settings
-	Parser string `yaml:"parser"` // Parser type (html, json, xml, rss)
+	Parser string `yaml:"parser"` // Parser type (html, json, xml, rss, jsonfeed, csv, tsv, auto or any registered type)
 
 	// Content extraction settings for HTML parsers
-	Selector map[string]string `yaml:"selector"` // CSS selectors for HTML parsing
+	Selector map[string]string `yaml:"selectors"` // CSS selectors for HTML parsing
+
+	// Read JSON-LD, microdata and OpenGraph markup before applying selectors (HTML parser)
+	StructuredData bool `yaml:"structured_data"`
+
+	// Main-content extraction; "article" finds the article body without a content selector (HTML parser)
+	Extract string `yaml:"extract"`
+
+	// Time allowed for each request to the source, overriding app.timeouts.request
+	Timeout time.Duration `yaml:"timeout"`
 
 	// Header settings
 	Headers map[string]string `yaml:"headers"` // Additional headers to include in requests
@@ -32,12 +52,30 @@ type Source struct {
 	// Content mapping for structured data (JSON, XML)
 	Mapping map[string]string `yaml:"mappings"` // Field mappings for structured data
 
+	// Namespace prefixes usable in XPath mappings (XML parser)
+	Namespaces map[string]string `yaml:"namespaces"` // Prefix -> namespace URI
+
+	// Delimited text settings (CSV and TSV parsers)
+	CSV struct {
+		Delimiter string `yaml:"delimiter"` // Field separator; "," for csv and tab for tsv by default
+		Quote     string `yaml:"quote"`     // Quote character, '"' by default; "none" disables quoting
+		NoHeader  bool   `yaml:"no_header"` // The first row is data; mappings must use column indexes
+		SkipRows  int    `yaml:"skip_rows"` // Leading rows to skip before the header or first record
+	} `yaml:"csv"`
+
+	// Date settings for extracted timestamps
+	DateFormat string `yaml:"date_format"` // Go layout, strftime pattern, "unix" or "unix_ms", tried first
+	Timezone   string `yaml:"timezone"`    // Zone for timestamps without one, e.g. "Europe/Berlin" or "+05:30"
+
 	// Pagnination settings
 	Pagination struct {
-		Enabled   bool   `yaml:"enabled"`    // Whether pagination is enabled
-		StartPage int    `yaml:"start_page"` // First page number
-		MaxPages  int    `yaml:"max_pages"`  // Maximum number of pages to fetch
-		ParamName string `yaml:"param_name"` // URL parameter for pagination
+		Enabled     bool   `yaml:"enabled"`      // Whether pagination is enabled
+		Strategy    string `yaml:"strategy"`     // page (default), link_header, html_next or json_cursor
+		StartPage   int    `yaml:"start_page"`   // First page number
+		MaxPages    int    `yaml:"max_pages"`    // Maximum number of pages to fetch
+		ParamName   string `yaml:"param_name"`   // URL parameter for pagination
+		CursorPath  string `yaml:"cursor_path"`  // JSON path of the next cursor (json_cursor)
+		CursorParam string `yaml:"cursor_param"` // URL parameter the cursor is sent in (json_cursor)
 	} `yaml:"pagination"`
 
 	// Sitemap settings
@@ -47,6 +85,63 @@ type Source struct {
 		MaxURLs    int    `yaml:"max_urls"`    // Maximum number of URLs to process
 		Pattern    string `yaml:"pattern"`     // Regex pattern for URL filtering
 	} `yaml:"sitemap"`
+
+	// Detail page settings, for listings that only show headlines and teasers
+	Detail struct {
+		Selectors  map[string]string `yaml:"selectors"`   // CSS selectors applied to the page at each item's URL
+		Extract    string            `yaml:"extract"`     // Main-content extraction on the detail page, e.g. "article"
+		MaxFetches int               `yaml:"max_fetches"` // Maximum detail pages fetched per run; 0 means no limit
+	} `yaml:"detail"`
+}
+
+// DetailEnabled reports whether the items of the source are followed into
+// their detail pages
+func (s *Source) DetailEnabled() bool {
+	return len(s.Detail.Selectors) > 0 || s.Detail.Extract != ""
+}
+
+// DetailSource returns the settings used to parse a detail page: the source
+// with the detail selectors and extraction mode in place of its own
+func (s *Source) DetailSource() *Source {
+	detail := *s
+	detail.Parser = "html"
+	detail.Selector = s.Detail.Selectors
+	detail.Extract = s.Detail.Extract
+	detail.StructuredData = false
+
+	return &detail
+}
+
+// Pagination strategies
+const (
+	PaginationPage       = "page"        // Numbered pages through ParamName or a ${page} placeholder
+	PaginationLinkHeader = "link_header" // RFC 5988 Link header with rel="next"
+	PaginationHTMLNext   = "html_next"   // <a rel="next"> or <link rel="next"> in the page
+	PaginationJSONCursor = "json_cursor" // Cursor read from the body at CursorPath
+)
+
+// PaginationStrategy returns the configured pagination strategy, defaulting to numbered pages
+func (s *Source) PaginationStrategy() string {
+	if s.Pagination.Strategy == "" {
+		return PaginationPage
+	}
+
+	return s.Pagination.Strategy
+}
+
+// GetURLWithCursor returns the URL with the cursor query parameter set
+func (s *Source) GetURLWithCursor(cursor string) string {
+	baseURL, err := url.Parse(s.URL)
+
+	if err != nil {
+		return s.URL + "&" + s.Pagination.CursorParam + "=" + url.QueryEscape(cursor)
+	}
+
+	query := baseURL.Query()
+	query.Set(s.Pagination.CursorParam, cursor)
+	baseURL.RawQuery = query.Encode()
+
+	return baseURL.String()
 }
 
 // ParseURL parses the source URL into a url.URL struct
@@ -112,19 +207,102 @@ func (s *Source) Validate() error {
 		return fmt.Errorf("parser type is required")
 	}
 
+	// Validate date settings
+	if _, err := dateparse.LoadLocation(s.Timezone); err != nil {
+		return err
+	}
+
+	// Validate pagination settings
+	if s.Pagination.Enabled {
+		if err := s.validatePagination(); err != nil {
+			return err
+		}
+	}
+
 	// Validate parser-specific settings
-	switch s.Parser {
-	case "html":
-		if len(s.Selector) == 0 {
-			return fmt.Errorf("HTML parser requires at least one selector")
+	parserTypesMu.RLock()
+	validate, registered := parserTypes[s.Parser]
+	parserTypesMu.RUnlock()
+
+	if !registered {
+		return fmt.Errorf("unsupported parser type '%s' (registered types: %s)", s.Parser, strings.Join(ParserTypes(), ", "))
+	}
+
+	if validate != nil {
+		if err := validate(s); err != nil {
+			return err
+		}
+	}
+
+	// Detail pages are parsed as HTML
+	if s.DetailEnabled() {
+		if s.Detail.MaxFetches < 0 {
+			return fmt.Errorf("detail.max_fetches must not be negative")
+		}
+
+		parserTypesMu.RLock()
+		validateHTML := parserTypes["html"]
+		parserTypesMu.RUnlock()
+
+		if validateHTML != nil {
+			if err := validateHTML(s.DetailSource()); err != nil {
+				return fmt.Errorf("detail: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePagination checks the settings required by the pagination strategy
+func (s *Source) validatePagination() error {
+	switch s.PaginationStrategy() {
+	case PaginationPage:
+		if s.Pagination.ParamName == "" && !strings.Contains(s.URL, "${page}") {
+			return fmt.Errorf("page pagination requires param_name or a ${page} placeholder in the URL")
 		}
-	case "json", "xml", "rss":
-		if len(s.Mapping) == 0 {
-			return fmt.Errorf("%s parser requires at least one mapping", strings.ToUpper(s.Parser))
+	case PaginationLinkHeader, PaginationHTMLNext:
+	case PaginationJSONCursor:
+		if s.Pagination.CursorPath == "" || s.Pagination.CursorParam == "" {
+			return fmt.Errorf("json_cursor pagination requires cursor_path and cursor_param")
 		}
 	default:
-		return fmt.Errorf("unsupported parser type '%s'", s.Parser)
+		return fmt.Errorf("unsupported pagination strategy '%s'", s.Pagination.Strategy)
+	}
+
+	if s.Pagination.MaxPages < 0 {
+		return fmt.Errorf("pagination max_pages cannot be negative")
 	}
 
 	return nil
 }
+
+var (
+	// parserTypes maps each parser type sources may use to its optional
+	// validation of parser-specific settings
+	parserTypes   = make(map[string]func(*Source) error)
+	parserTypesMu sync.RWMutex
+)
+
+// RegisterParserType declares a parser type that sources may reference. The
+// parser package calls it for every registered parser; validate may be nil.
+func RegisterParserType(name string, validate func(*Source) error) {
+	parserTypesMu.Lock()
+	defer parserTypesMu.Unlock()
+
+	parserTypes[name] = validate
+}
+
+// ParserTypes returns the names of all declared parser types, sorted
+func ParserTypes() []string {
+	parserTypesMu.RLock()
+	defer parserTypesMu.RUnlock()
+
+	types := make([]string, 0, len(parserTypes))
+	for name := range parserTypes {
+		types = append(types, name)
+	}
+
+	sort.Strings(types)
+	return types
+}
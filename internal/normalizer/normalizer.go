@@ -7,138 +7,272 @@ import (
 	"sync"
 	"time"
 
-	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/aggregator"
-	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/parser"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
 )
 
-// Normalizer transforms raw content into a normalized format.
-// before sending it to the result aggregator.
+// Normalizer converts the items extracted by the parsers into the result
+// items written to the outputs. The core fields of an item map directly onto
+// the result; the well-known extra fields set by the parsers fill in the
+// description, type, categories, tags, images and links, and every other
+// extra field is kept in the result's metadata.
 type Normalizer struct {
-	aggregator   *aggregator.Aggregator
 	normalizeMap map[string]NormalizerFunc
-	mu           sync.Mutex
+	mu           sync.RWMutex
 }
 
-// NormalizerFunc is a function that normalizes data for a specific content type
-type NormalizerFunc func(*parser.Parser) (*aggregator.Aggregator, error)
+// NormalizerFunc adjusts a result after the default conversion, for the
+// items of one parser type
+type NormalizerFunc func(item *model.Item, result *model.ResultItem) error
 
-// New creates a new Normalizer instance with the provided aggregator.
-func New(agg *aggregator.Aggregator) (*Normalizer, error) {
-	if agg == nil {
-		return nil, fmt.Errorf("invalid aggregator: %w", aggregator.ErrInvalidAggregator)
-	}
+// consumedFields are the extra fields that become result fields rather than metadata
+var consumedFields = map[string]bool{
+	"description": true, "summary": true, "type": true, "categories": true,
+	"tags": true, "keywords": true, "image": true, "images": true,
+	"links": true, "external_url": true,
+}
 
-	n := &Normalizer{
-		aggregator:   agg,
+// New creates a new Normalizer
+func New() *Normalizer {
+	return &Normalizer{
 		normalizeMap: make(map[string]NormalizerFunc),
 	}
-
-	// Register default normalizers
-	n.RegisterNormalizer("text", normalizeText)
-	n.RegisterNormalizer("html", normalizeHTML)
-	n.RegisterNormalizer("json", normalizeJSON) // Example for JSON, can be replaced with actual JSON normalization
-	n.RegisterNormalizer("rss", normalizeRSS)
-
-	return n, nil
 }
 
-// Normalize processes the content using the appropriate normalizer based on the content type.
-func (n *Normalizer) Normalize(p *parser.Parser) (*aggregator.Aggregator, error) {
+// RegisterNormalizer adds a conversion step for the items of a parser type,
+// matched against Item.ExtractedBy
+func (n *Normalizer) RegisterNormalizer(parserType string, normalizeFunc NormalizerFunc) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
-	contentType := p.GetContentType()
-	if normalizeFunc, exists := n.normalizeMap[contentType]; exists {
-		log.Printf("Normalizing content of type '%s'", contentType)
-		startTime := time.Now()
-		normalizedContent, err := normalizeFunc(p)
+
+	if _, exists := n.normalizeMap[parserType]; exists {
+		log.Printf("Normalizer for parser type '%s' already exists, overwriting", parserType)
+	}
+	n.normalizeMap[parserType] = normalizeFunc
+}
+
+// NormalizeAll converts the items extracted from one fetched content. Items
+// whose registered normalizer fails are logged and left out.
+func (n *Normalizer) NormalizeAll(items []model.Item, content *model.Content) []model.ResultItem {
+	results := make([]model.ResultItem, 0, len(items))
+
+	for i := range items {
+		result, err := n.Normalize(&items[i], content)
 		if err != nil {
-			return nil, fmt.Errorf("normalization failed for content type '%s': %w", contentType, err)
+			log.Printf("Warning: Dropping item %s from %s: %v", items[i].ID, items[i].SourceName, err)
+			continue
 		}
-		duration := time.Since(startTime)
-		log.Printf("Normalization completed for content type '%s' in %s", contentType, duration)
-		return normalizedContent, nil
+		results = append(results, *result)
 	}
 
-	log.Printf("No normalizer registered for content type '%s', returning raw content", contentType)
-	return &aggregator.Aggregator{
-		Content: p.GetContent(),
-	}, nil
+	return results
 }
 
-func (n *Normalizer) RegisterNormalizer(contentType string, normalizeFunc NormalizerFunc) {
-	n.mu.Lock()
-	defer n.mu.Unlock()
+// Normalize converts a single item. The content is the fetch the item was
+// extracted from and links the result back to it through ContentID.
+func (n *Normalizer) Normalize(item *model.Item, content *model.Content) (*model.ResultItem, error) {
+	contentID := ""
+	if content != nil {
+		contentID = content.ID
+	}
 
-	if _, exists := n.normalizeMap[contentType]; exists {
-		log.Printf("Normalizer for content type '%s' already exists, overwriting", contentType)
+	// Sources built outside the configuration may have no ID
+	sourceID := item.SourceID
+	if sourceID == "" {
+		sourceID = item.SourceName
 	}
-	n.normalizeMap[contentType] = normalizeFunc
+
+	result := model.NewResultItem(sourceID, contentID, item.URL)
+	if item.ID != "" {
+		result.ID = item.ID
+	}
+
+	result.SetTitle(strings.TrimSpace(item.Title))
+	result.SetContent(strings.TrimSpace(item.Content))
+	result.SetDescription(strings.TrimSpace(firstString(item.ExtraFields, "description", "summary")))
+	result.SetTimestamp(item.Date)
+	result.SetAuthor(strings.TrimSpace(item.Author))
+	result.SetType(resultType(item))
+
+	for _, category := range dedupe(append(splitList(item.Category), stringList(item.ExtraFields["categories"])...)) {
+		result.AddCategory(category)
+	}
+
+	for _, tag := range dedupe(append(stringList(item.ExtraFields["tags"]), stringList(item.ExtraFields["keywords"])...)) {
+		result.AddTag(tag)
+	}
+
+	images := append(urlList(item.ExtraFields["image"]), urlList(item.ExtraFields["images"])...)
+	links := append(urlList(item.ExtraFields["external_url"]), urlList(item.ExtraFields["links"])...)
+
+	// Attachments are images or related links depending on their media type
+	for _, enc := range enclosures(item.ExtraFields["enclosures"]) {
+		href, _ := enc["url"].(string)
+		if mediaType, _ := enc["type"].(string); strings.HasPrefix(mediaType, "image/") {
+			images = append(images, href)
+		} else {
+			links = append(links, href)
+		}
+	}
+
+	for _, image := range dedupe(images) {
+		result.AddImage(image)
+	}
+
+	for _, link := range dedupe(links) {
+		if link != item.URL {
+			result.AddLink(link)
+		}
+	}
+
+	for key, value := range item.ExtraFields {
+		if !consumedFields[key] {
+			result.AddMetadata(key, metadataValue(value))
+		}
+	}
+
+	// Provenance
+	result.AddMetadata("source_name", item.SourceName)
+	result.AddMetadata("source_url", item.SourceURL)
+	result.AddMetadata("extracted_by", item.ExtractedBy)
+	if !item.FetchedAt.IsZero() {
+		result.AddMetadata("fetched_at", item.FetchedAt.Format(time.RFC3339))
+	}
+	if !item.ParsedAt.IsZero() {
+		result.AddMetadata("parsed_at", item.ParsedAt.Format(time.RFC3339))
+	}
+
+	n.mu.RLock()
+	normalizeFunc, exists := n.normalizeMap[item.ExtractedBy]
+	n.mu.RUnlock()
+
+	if exists {
+		if err := normalizeFunc(item, result); err != nil {
+			return nil, fmt.Errorf("normalizer for '%s' failed: %w", item.ExtractedBy, err)
+		}
+	}
+
+	return result, nil
 }
 
-func normalizeHTML(p *parser.Parser) (*aggregator.Aggregator, error) {
-	// Example normalization logic for HTML content
-	content := p.GetContent()
-	if content == "" {
-		return nil, fmt.Errorf("no content to normalize")
+// resultType reads the type set by the structured data reader, and
+// otherwise treats feed entries as articles
+func resultType(item *model.Item) model.ResultType {
+	switch t := model.ResultType(firstString(item.ExtraFields, "type")); t {
+	case model.ResultTypeArticle, model.ResultTypeNews, model.ResultTypeBlogPost,
+		model.ResultTypeProduct, model.ResultTypeEvent, model.ResultTypeMedia:
+		return t
 	}
 
-	// Simulate normalization process
-	normalizedContent := strings.TrimSpace(content)
-	log.Printf("Normalized HTML content: %s", normalizedContent)
+	switch item.ExtractedBy {
+	case "rss", "jsonfeed":
+		return model.ResultTypeArticle
+	}
 
-	// Create a new aggregator instance with the normalized content
-	return &aggregator.Aggregator{
-		Content: normalizedContent,
-	}, nil
+	return model.ResultTypeOther
 }
 
-func normalizeText(p *parser.Parser) (*aggregator.Aggregator, error) {
-	// Example normalization logic for text content
-	content := p.GetContent()
-	if content == "" {
-		return nil, fmt.Errorf("no content to normalize")
+// firstString returns the first of the keys holding a non-empty string
+func firstString(fields map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := fields[key].(string); ok && strings.TrimSpace(s) != "" {
+			return s
+		}
 	}
 
-	// Simulate normalization process
-	normalizedContent := strings.TrimSpace(content)
-	log.Printf("Normalized text content: %s", normalizedContent)
+	return ""
+}
+
+// stringList reads a list field, which parsers store as a delimited string,
+// a []string or a decoded JSON array
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return splitList(v)
+	case []string:
+		return v
+	case []interface{}:
+		var list []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				list = append(list, s)
+			} else if e != nil {
+				list = append(list, fmt.Sprint(e))
+			}
+		}
+		return list
+	}
 
-	// Create a new aggregator instance with the normalized content
-	return &aggregator.Aggregator{
-		Content: normalizedContent,
-	}, nil
+	return nil
 }
 
-func normalizeJSON(p *parser.Parser) (*aggregator.Aggregator, error) {
-	// Example normalization logic for JSON content
-	content := p.GetContent()
-	if content == "" {
-		return nil, fmt.Errorf("no content to normalize")
+// urlList reads a field holding one URL or a list of them; URLs may contain
+// the delimiters splitList splits on
+func urlList(value interface{}) []string {
+	if s, ok := value.(string); ok {
+		return []string{s}
 	}
 
-	// Simulate normalization process
-	normalizedContent := strings.TrimSpace(content)
-	log.Printf("Normalized JSON content: %s", normalizedContent)
+	return stringList(value)
+}
+
+// splitList splits categories and tags written as "a, b", "a; b" or "a | b"
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	})
+}
+
+// dedupe trims the values and drops empty and repeated ones, keeping the
+// first spelling of values that differ only in case
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		key := strings.ToLower(v)
+		if v == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, v)
+	}
 
-	// Create a new aggregator instance with the normalized content
-	return &aggregator.Aggregator{
-		Content: normalizedContent,
-	}, nil
+	return out
 }
 
-func normalizeRSS(p *parser.Parser) (*aggregator.Aggregator, error) {
-	// Example normalization logic for RSS content
-	content := p.GetContent()
-	if content == "" {
-		return nil, fmt.Errorf("no content to normalize")
+// enclosures reads the attachments stored by the feed parsers
+func enclosures(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		var list []map[string]interface{}
+		for _, e := range v {
+			if m, ok := e.(map[string]interface{}); ok {
+				list = append(list, m)
+			}
+		}
+		return list
 	}
 
-	// Simulate normalization process
-	normalizedContent := strings.TrimSpace(content)
-	log.Printf("Normalized RSS content: %s", normalizedContent)
+	return nil
+}
+
+// metadataValue converts an extra field into a value every output format can
+// encode: times become RFC 3339 strings and errors their message
+func metadataValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
 
-	// Create a new aggregator instance with the normalized content
-	return &aggregator.Aggregator{
-		Content: normalizedContent,
-	}, nil
+	return value
 }
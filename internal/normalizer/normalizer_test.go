@@ -0,0 +1,74 @@
+package normalizer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+func TestNormalize(t *testing.T) {
+	date := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	item := &model.Item{
+		ID:       "abc123",
+		Title:    " Rates held ",
+		Content:  "<p>The bank held rates.</p>",
+		URL:      "https://example.com/news/1",
+		Date:     date,
+		Author:   "Jane Doe",
+		Category: "Economy; Markets",
+		ExtraFields: map[string]interface{}{
+			"summary":    "Rates unchanged",
+			"categories": []string{"economy", "Banking"},
+			"tags":       []interface{}{"rates", "ecb"},
+			"enclosures": []map[string]interface{}{
+				{"url": "https://example.com/chart.png", "type": "image/png"},
+				{"url": "https://example.com/podcast.mp3", "type": "audio/mpeg"},
+			},
+			"guid":  "tag:example.com,2024:1",
+			"price": 9.5,
+		},
+		SourceID:    "example-feed",
+		SourceName:  "Example",
+		SourceURL:   "https://example.com/feed",
+		ExtractedBy: "rss",
+	}
+
+	result, err := New().Normalize(item, &model.Content{ID: "content-1"})
+	if err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+
+	if result.ID != "abc123" || result.SourceID != "example-feed" || result.ContentID != "content-1" {
+		t.Errorf("Normalize() got ID = %q, SourceID = %q, ContentID = %q", result.ID, result.SourceID, result.ContentID)
+	}
+
+	if result.Title != "Rates held" || result.Description != "Rates unchanged" || !result.Timestamp.Equal(date) {
+		t.Errorf("Normalize() got Title = %q, Description = %q, Timestamp = %v", result.Title, result.Description, result.Timestamp)
+	}
+
+	if result.Type != model.ResultTypeArticle {
+		t.Errorf("Normalize() got Type = %q, want %q", result.Type, model.ResultTypeArticle)
+	}
+
+	if want := []string{"Economy", "Markets", "Banking"}; !reflect.DeepEqual(result.Categories, want) {
+		t.Errorf("Normalize() got Categories = %v, want %v", result.Categories, want)
+	}
+
+	if want := []string{"rates", "ecb"}; !reflect.DeepEqual(result.Tags, want) {
+		t.Errorf("Normalize() got Tags = %v, want %v", result.Tags, want)
+	}
+
+	if len(result.Images) != 1 || len(result.Links) != 1 || result.Links[0] != "https://example.com/podcast.mp3" {
+		t.Errorf("Normalize() got Images = %v, Links = %v", result.Images, result.Links)
+	}
+
+	if result.Metadata["price"] != 9.5 || result.Metadata["guid"] != "tag:example.com,2024:1" || result.Metadata["extracted_by"] != "rss" {
+		t.Errorf("Normalize() got Metadata = %v", result.Metadata)
+	}
+
+	if _, ok := result.Metadata["summary"]; ok {
+		t.Error("Normalize() kept a field converted to Description in Metadata")
+	}
+}
@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// AutoParser picks the parser for each response from its Content-Type, the
+// start of the body and the URL extension, then delegates to it. It suits
+// sources whose format is unknown in advance or changes between fetches.
+type AutoParser struct{}
+
+// Parse detects the content format and runs the matching parser
+func (p *AutoParser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	if content == nil || len(content.Body) == 0 {
+		return nil, fmt.Errorf("no content to parse")
+	}
+
+	parserType, reason := detectParser(content)
+	if parserType == "" {
+		return nil, fmt.Errorf("could not detect the format of %s (Content-Type %q)", content.URL, content.ContentType)
+	}
+
+	delegate, err := Get(parserType)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Auto parser: using '%s' parser for %s (%s)", parserType, source.Name, reason)
+
+	// The source settings could only be checked once the format was known;
+	// without them a parser such as json would quietly find nothing
+	if v, ok := delegate.(SourceValidator); ok {
+		if err := v.Validate(source); err != nil {
+			return nil, fmt.Errorf("detected %s content, but the source does not suit the %s parser: %w", parserType, parserType, err)
+		}
+	}
+
+	items, err := delegate.Parse(ctx, content, source)
+
+	// Parsers registered by users may not fill in the provenance
+	for i := range items {
+		if items[i].ExtractedBy == "" {
+			items[i].ExtractedBy = parserType
+		}
+	}
+
+	return items, err
+}
+
+// detectParser returns the parser type for the content and a short
+// description of how it was chosen, or an empty type when nothing matched.
+// The Content-Type wins, except that feeds and JSON are recognised by their
+// body when served as generic XML, JSON or HTML; the URL extension is the
+// last resort.
+func detectParser(content *model.Content) (string, string) {
+	mediaType, _, err := mime.ParseMediaType(content.ContentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(content.ContentType))
+	}
+
+	byType := parserForMediaType(mediaType)
+	sniffed := sniffParser(content.Body)
+
+	switch {
+	case byType == "xml" && sniffed != "", byType == "json" && sniffed == "jsonfeed",
+		byType == "html" && (sniffed == "rss" || sniffed == "json" || sniffed == "jsonfeed"):
+		// Feeds are routinely served as text/xml, and sometimes even as text/html
+		return sniffed, fmt.Sprintf("Content-Type %s, body starts like %s", mediaType, sniffed)
+	case byType != "":
+		return byType, "Content-Type " + mediaType
+	case sniffed != "":
+		return sniffed, "body starts like " + sniffed
+	}
+
+	if byExt := parserForURL(content.URL); byExt != "" {
+		return byExt, "URL extension"
+	}
+
+	return "", ""
+}
+
+// parserForMediaType maps a MIME type to a parser type
+func parserForMediaType(mediaType string) string {
+	switch mediaType {
+	case "application/rss+xml", "application/atom+xml", "application/rdf+xml":
+		return "rss"
+	case "application/feed+json":
+		return "jsonfeed"
+	case "application/json", "text/json", "application/ld+json":
+		return "json"
+	case "text/html", "application/xhtml+xml":
+		return "html"
+	case "application/xml", "text/xml":
+		return "xml"
+	case "text/csv", "application/csv":
+		return "csv"
+	case "text/tab-separated-values":
+		return "tsv"
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case strings.HasSuffix(mediaType, "+xml"):
+		return "xml"
+	}
+
+	return ""
+}
+
+// parserForURL maps the extension of the URL path to a parser type
+func parserForURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	// The fetcher has already decompressed resources such as feed.xml.gz
+	name := strings.TrimSuffix(strings.ToLower(u.Path), ".gz")
+
+	switch path.Ext(name) {
+	case ".rss", ".atom", ".rdf":
+		return "rss"
+	case ".json":
+		return "json"
+	case ".xml":
+		return "xml"
+	case ".csv":
+		return "csv"
+	case ".tsv", ".tab":
+		return "tsv"
+	case ".html", ".htm", ".xhtml", ".php", ".asp", ".aspx", ".jsp":
+		return "html"
+	}
+
+	return ""
+}
+
+// sniffParser inspects the start of the body, skipping a byte order mark,
+// whitespace, the XML prolog, comments and processing instructions, and
+// returns the parser type suggested by the first significant token
+func sniffParser(body []byte) string {
+	b := bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+
+	for {
+		b = bytes.TrimLeft(b, " \t\r\n")
+		if len(b) == 0 {
+			return ""
+		}
+
+		switch b[0] {
+		case '{':
+			// JSON Feed documents announce themselves in their version
+			if bytes.Contains(b, []byte("jsonfeed.org/version")) {
+				return "jsonfeed"
+			}
+			return "json"
+		case '[':
+			return "json"
+		case '<':
+		default:
+			return ""
+		}
+
+		switch {
+		case bytes.HasPrefix(b, []byte("<?")):
+			b = skipPast(b, "?>")
+		case bytes.HasPrefix(b, []byte("<!--")):
+			b = skipPast(b, "-->")
+		case hasPrefixFold(b, "<!doctype"):
+			if hasPrefixFold(bytes.TrimLeft(b[len("<!doctype"):], " \t\r\n"), "html") {
+				return "html"
+			}
+			b = skipPast(b, ">")
+		default:
+			return parserForRoot(rootName(b[1:]))
+		}
+	}
+}
+
+// parserForRoot maps the name of the first element in a document to a parser type
+func parserForRoot(name string) string {
+	local := name
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		local = name[i+1:]
+	}
+
+	switch strings.ToLower(local) {
+	case "":
+		return ""
+	case "rss", "feed", "rdf", "channel":
+		return "rss"
+	case "html", "head", "body":
+		return "html"
+	}
+
+	return "xml"
+}
+
+// rootName reads the element name at the start of b
+func rootName(b []byte) string {
+	end := 0
+	for end < len(b) && isTagNameChar(b[end]) {
+		end++
+	}
+
+	return string(b[:end])
+}
+
+// skipPast returns the part of b after the first occurrence of marker
+func skipPast(b []byte, marker string) []byte {
+	i := bytes.Index(b, []byte(marker))
+	if i < 0 {
+		return nil
+	}
+
+	return b[i+len(marker):]
+}
+
+func hasPrefixFold(b []byte, prefix string) bool {
+	return len(b) >= len(prefix) && strings.EqualFold(string(b[:len(prefix)]), prefix)
+}
@@ -0,0 +1,323 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// CsvParser extracts one item per row of a CSV or TSV download. Mappings
+// name a header column ("Headline") or give a zero-based column index ("2");
+// columns that are not mapped are kept in ExtraFields under their header
+// name, or as "column_N" when the file has no header row. Delimiter, quote
+// character, header row and leading rows to skip are set in Source.CSV.
+type CsvParser struct {
+	tab bool // Tab-separated by default rather than comma-separated
+}
+
+// csvDialect describes how the fields of a delimited file are written
+type csvDialect struct {
+	delimiter rune
+	quote     rune // Zero when quoting is disabled
+}
+
+// Parse extracts items from a delimited text document
+func (p *CsvParser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	if content == nil || len(content.Body) == 0 {
+		return nil, fmt.Errorf("no %s content to parse", p.name())
+	}
+
+	dialect, err := p.dialect(source)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.TrimPrefix(content.Body, []byte("\xef\xbb\xbf"))
+	records, err := readDelimited(skipLines(body, source.CSV.SkipRows), dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	var header []string
+	if !source.CSV.NoHeader && len(records) > 0 {
+		header, records = records[0], records[1:]
+	}
+
+	columns, err := csvColumns(source, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// Columns that are not mapped to a field are kept in ExtraFields
+	mapped := make(map[int]bool, len(columns))
+	for _, col := range columns {
+		mapped[col] = true
+	}
+
+	fields := sortedMappingKeys(source.Mapping)
+	items := make([]model.Item, 0, len(records))
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+
+		item := newItem(content, source, p.name())
+		var missing []string
+
+		for _, field := range fields {
+			col := columns[field]
+			if col >= len(record) || strings.TrimSpace(record[col]) == "" {
+				missing = append(missing, field)
+				continue
+			}
+
+			value := strings.TrimSpace(record[col])
+			if field == "url" || field == "link" {
+				value = resolveURL(content.URL, value)
+			}
+			setItemField(&item, source, field, value)
+		}
+
+		for col, value := range record {
+			if mapped[col] || strings.TrimSpace(value) == "" {
+				continue
+			}
+			item.ExtraFields[csvColumnName(header, col)] = strings.TrimSpace(value)
+		}
+
+		if len(missing) > 0 {
+			item.ExtraFields["missing_fields"] = missing
+		}
+
+		if item.ID == "" && item.URL == "" && item.Title == "" {
+			// Nothing identifies the row but its values
+			item.ID = itemID(source.Name, strings.Join(record, "\x1f"))
+		} else {
+			item.ID = itemID(source.Name, firstNonEmpty(item.ID, item.URL), item.Title)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Validate checks the source's CSV settings and, for files without a header
+// row, that every mapping is a column index
+func (p *CsvParser) Validate(source *model.Source) error {
+	if _, err := p.dialect(source); err != nil {
+		return err
+	}
+
+	if source.CSV.SkipRows < 0 {
+		return fmt.Errorf("csv.skip_rows must not be negative")
+	}
+
+	if source.CSV.NoHeader {
+		if len(source.Mapping) == 0 {
+			return fmt.Errorf("%s parser without a header row requires at least one mapping", p.name())
+		}
+
+		for field, ref := range source.Mapping {
+			if field == containerKey {
+				continue
+			}
+
+			if _, err := strconv.Atoi(strings.TrimSpace(ref)); err != nil {
+				return fmt.Errorf("mapping '%s': %q is not a column index, which is required without a header row", field, ref)
+			}
+		}
+	}
+
+	return nil
+}
+
+// name returns the parser type, which is recorded as the item's ExtractedBy
+func (p *CsvParser) name() string {
+	if p.tab {
+		return "tsv"
+	}
+
+	return "csv"
+}
+
+// dialect resolves the delimiter and quote character configured for a source
+func (p *CsvParser) dialect(source *model.Source) (csvDialect, error) {
+	d := csvDialect{delimiter: ',', quote: '"'}
+	if p.tab {
+		d.delimiter = '\t'
+	}
+
+	switch delim := source.CSV.Delimiter; delim {
+	case "":
+	case "tab", `\t`:
+		d.delimiter = '\t'
+	default:
+		r, size := utf8.DecodeRuneInString(delim)
+		if size != len(delim) || r == '\r' || r == '\n' {
+			return d, fmt.Errorf("csv.delimiter must be a single character, got %q", delim)
+		}
+		d.delimiter = r
+	}
+
+	switch quote := source.CSV.Quote; quote {
+	case "":
+	case "none":
+		d.quote = 0
+	default:
+		r, size := utf8.DecodeRuneInString(quote)
+		if size != len(quote) || r == '\r' || r == '\n' {
+			return d, fmt.Errorf("csv.quote must be a single character or \"none\", got %q", quote)
+		}
+		d.quote = r
+	}
+
+	if d.quote == d.delimiter {
+		return d, fmt.Errorf("csv.quote and csv.delimiter must differ")
+	}
+
+	return d, nil
+}
+
+// csvColumns resolves each mapping to a column index. A mapping names a
+// header column, matched case-insensitively, or gives a zero-based index.
+func csvColumns(source *model.Source, header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(source.Mapping))
+
+	for field, ref := range source.Mapping {
+		if field == containerKey {
+			// Every row is an item; there is nothing to select
+			continue
+		}
+
+		ref = strings.TrimSpace(ref)
+		col := -1
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), ref) {
+				col = i
+				break
+			}
+		}
+
+		if col < 0 {
+			i, err := strconv.Atoi(ref)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("mapping '%s': no column %q in the header row", field, ref)
+			}
+			col = i
+		}
+
+		columns[field] = col
+	}
+
+	return columns, nil
+}
+
+// csvColumnName names an unmapped column in ExtraFields
+func csvColumnName(header []string, col int) string {
+	if col < len(header) {
+		if name := strings.TrimSpace(header[col]); name != "" {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("column_%d", col)
+}
+
+// skipLines drops the first n lines of a document, such as a title or
+// copyright notice above the header row
+func skipLines(body []byte, n int) []byte {
+	for ; n > 0 && len(body) > 0; n-- {
+		i := bytes.IndexByte(body, '\n')
+		if i < 0 {
+			return nil
+		}
+		body = body[i+1:]
+	}
+
+	return body
+}
+
+// readDelimited splits a document into records. Quoted fields may contain
+// the delimiter, line breaks and doubled quote characters; a quote character
+// inside an unquoted field is kept as it is, and blank lines are skipped.
+func readDelimited(body []byte, d csvDialect) ([][]string, error) {
+	var records [][]string
+	var record []string
+	var field strings.Builder
+
+	text := string(body)
+	line := 1
+	quoted := false
+	quoteLine := 0
+	atFieldStart := true
+
+	endField := func() {
+		record = append(record, field.String())
+		field.Reset()
+		atFieldStart = true
+	}
+
+	endRecord := func() {
+		endField()
+		if len(record) > 1 || record[0] != "" {
+			records = append(records, record)
+		}
+		record = nil
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+
+		if quoted {
+			switch {
+			case r == d.quote && strings.HasPrefix(text[i:], string(d.quote)):
+				// A doubled quote is a literal quote
+				field.WriteRune(r)
+				i += size
+			case r == d.quote:
+				quoted = false
+			default:
+				if r == '\n' {
+					line++
+				}
+				field.WriteRune(r)
+			}
+			continue
+		}
+
+		switch {
+		case r == d.quote && d.quote != 0 && atFieldStart:
+			quoted = true
+			quoteLine = line
+			atFieldStart = false
+		case r == d.delimiter:
+			endField()
+		case r == '\r' && strings.HasPrefix(text[i:], "\n"):
+			// Handled with the following line feed
+		case r == '\n' || r == '\r':
+			line++
+			endRecord()
+		default:
+			field.WriteRune(r)
+			atFieldStart = false
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quoted field starting on line %d", quoteLine)
+	}
+
+	if len(record) > 0 || field.Len() > 0 {
+		endRecord()
+	}
+
+	return records, nil
+}
@@ -0,0 +1,461 @@
+package parser
+
+import (
+	"html"
+	"strings"
+)
+
+// nodeType identifies the kind of a node in a parsed HTML document
+type nodeType int
+
+const (
+	documentNode nodeType = iota
+	elementNode
+	textNode
+	commentNode
+	doctypeNode
+)
+
+// htmlAttr is a single attribute on an HTML element
+type htmlAttr struct {
+	Key string
+	Val string
+}
+
+// htmlNode is a node in the lightweight DOM built by parseHTML
+type htmlNode struct {
+	Type     nodeType
+	Tag      string // Lower-cased tag name for elements
+	Attrs    []htmlAttr
+	Data     string // Text for text, comment and doctype nodes
+	Parent   *htmlNode
+	Children []*htmlNode
+}
+
+// voidElements never have content or an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements contain text that must not be parsed as markup
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+	"xmp": true, "noscript": true,
+}
+
+// autoClosers lists, for an element, the open elements that it implicitly closes
+var autoClosers = map[string][]string{
+	"p":          {"p"},
+	"li":         {"li"},
+	"dt":         {"dt", "dd"},
+	"dd":         {"dt", "dd"},
+	"tr":         {"tr", "td", "th"},
+	"td":         {"td", "th"},
+	"th":         {"td", "th"},
+	"option":     {"option"},
+	"thead":      {"tbody", "tfoot"},
+	"tbody":      {"thead", "tbody", "tfoot"},
+	"tfoot":      {"thead", "tbody"},
+	"div":        {"p"},
+	"ul":         {"p"},
+	"ol":         {"p"},
+	"table":      {"p"},
+	"h1":         {"p"},
+	"h2":         {"p"},
+	"h3":         {"p"},
+	"h4":         {"p"},
+	"h5":         {"p"},
+	"h6":         {"p"},
+	"section":    {"p"},
+	"article":    {"p"},
+	"header":     {"p"},
+	"footer":     {"p"},
+	"nav":        {"p"},
+	"aside":      {"p"},
+	"form":       {"p"},
+	"pre":        {"p"},
+	"figure":     {"p"},
+	"blockquote": {"p"},
+}
+
+// scopeBoundaries stop the search for an element to implicitly close
+var scopeBoundaries = map[string]bool{
+	"table": true, "ul": true, "ol": true, "dl": true, "select": true,
+	"html": true, "body": true, "td": true, "th": true,
+}
+
+// parseHTML builds a forgiving DOM from an HTML document. It never fails:
+// malformed markup is repaired the same way browsers mostly do, by implicitly
+// closing elements and ignoring stray end tags.
+func parseHTML(src string) *htmlNode {
+	doc := &htmlNode{Type: documentNode}
+	stack := []*htmlNode{doc}
+
+	current := func() *htmlNode {
+		return stack[len(stack)-1]
+	}
+
+	appendChild := func(n *htmlNode) {
+		parent := current()
+		n.Parent = parent
+		parent.Children = append(parent.Children, n)
+	}
+
+	// closeTo pops the stack up to and including the innermost element with the given tag
+	closeTo := func(tag string, boundaries map[string]bool) bool {
+		for i := len(stack) - 1; i > 0; i-- {
+			if stack[i].Tag == tag {
+				stack = stack[:i]
+				return true
+			}
+
+			if boundaries != nil && boundaries[stack[i].Tag] {
+				return false
+			}
+		}
+
+		return false
+	}
+
+	pos := 0
+	for pos < len(src) {
+		lt := strings.IndexByte(src[pos:], '<')
+		if lt < 0 {
+			appendText(current(), src[pos:], appendChild)
+			break
+		}
+
+		if lt > 0 {
+			appendText(current(), src[pos:pos+lt], appendChild)
+		}
+
+		pos += lt
+		rest := src[pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				appendChild(&htmlNode{Type: commentNode, Data: rest[4:]})
+				pos = len(src)
+			} else {
+				appendChild(&htmlNode{Type: commentNode, Data: rest[4 : 4+end]})
+				pos += 4 + end + 3
+			}
+
+		case strings.HasPrefix(rest, "<![CDATA["):
+			end := strings.Index(rest, "]]>")
+			if end < 0 {
+				appendText(current(), rest[9:], appendChild)
+				pos = len(src)
+			} else {
+				appendText(current(), rest[9:end], appendChild)
+				pos += end + 3
+			}
+
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				pos = len(src)
+				continue
+			}
+
+			if strings.HasPrefix(strings.ToLower(rest), "<!doctype") {
+				appendChild(&htmlNode{Type: doctypeNode, Data: strings.TrimSpace(rest[9:end])})
+			}
+			pos += end + 1
+
+		case strings.HasPrefix(rest, "</"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				pos = len(src)
+				continue
+			}
+
+			tag := strings.ToLower(strings.TrimSpace(rest[2:end]))
+			if i := strings.IndexAny(tag, " \t\r\n/"); i >= 0 {
+				tag = tag[:i]
+			}
+
+			closeTo(tag, nil)
+			pos += end + 1
+
+		default:
+			tag, attrs, selfClosing, n := readStartTag(rest)
+			if n == 0 {
+				// Not a tag after all, treat the '<' as text
+				appendText(current(), "<", appendChild)
+				pos++
+				continue
+			}
+			pos += n
+
+			for _, closed := range autoClosers[tag] {
+				closeTo(closed, scopeBoundaries)
+			}
+
+			el := &htmlNode{Type: elementNode, Tag: tag, Attrs: attrs}
+			appendChild(el)
+
+			if voidElements[tag] || selfClosing {
+				continue
+			}
+
+			if rawTextElements[tag] {
+				closing := "</" + tag
+				end := indexFold(src[pos:], closing)
+				if end < 0 {
+					end = len(src) - pos
+				}
+
+				text := src[pos : pos+end]
+				if text != "" {
+					if tag == "title" || tag == "textarea" {
+						text = html.UnescapeString(text)
+					}
+					el.Children = append(el.Children, &htmlNode{Type: textNode, Data: text, Parent: el})
+				}
+
+				pos += end
+				if gt := strings.IndexByte(src[pos:], '>'); gt >= 0 {
+					pos += gt + 1
+				} else {
+					pos = len(src)
+				}
+				continue
+			}
+
+			stack = append(stack, el)
+		}
+	}
+
+	return doc
+}
+
+// appendText adds decoded text to the current node, merging with a preceding text node
+func appendText(parent *htmlNode, raw string, appendChild func(*htmlNode)) {
+	if raw == "" {
+		return
+	}
+
+	text := html.UnescapeString(raw)
+	if n := len(parent.Children); n > 0 && parent.Children[n-1].Type == textNode {
+		parent.Children[n-1].Data += text
+		return
+	}
+
+	appendChild(&htmlNode{Type: textNode, Data: text})
+}
+
+// readStartTag parses a start tag at the beginning of s. It returns the
+// number of bytes consumed, or 0 if s does not start with a valid tag.
+func readStartTag(s string) (tag string, attrs []htmlAttr, selfClosing bool, n int) {
+	i := 1
+	start := i
+	for i < len(s) && isTagNameChar(s[i]) {
+		i++
+	}
+
+	if i == start || !isASCIILetter(s[start]) {
+		return "", nil, false, 0
+	}
+
+	tag = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		// Skip whitespace between attributes
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+
+		if i >= len(s) {
+			break
+		}
+
+		if s[i] == '>' {
+			return tag, attrs, selfClosing, i + 1
+		}
+
+		if s[i] == '/' {
+			selfClosing = true
+			i++
+			continue
+		}
+		selfClosing = false
+
+		// Attribute name
+		nameStart := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && !(s[i] == '/' && i+1 < len(s) && s[i+1] == '>') {
+			i++
+		}
+		name := strings.ToLower(s[nameStart:i])
+
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				i++
+				valStart := i
+				for i < len(s) && s[i] != quote {
+					i++
+				}
+				value = s[valStart:i]
+				if i < len(s) {
+					i++
+				}
+			} else {
+				valStart := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[valStart:i]
+			}
+		}
+
+		if name != "" {
+			attrs = append(attrs, htmlAttr{Key: name, Val: html.UnescapeString(value)})
+		} else if i == nameStart {
+			i++
+		}
+	}
+
+	// Unterminated tag: consume the rest of the input
+	return tag, attrs, selfClosing, len(s)
+}
+
+// indexFold returns the index of the first case-insensitive occurrence of substr in s
+func indexFold(s, substr string) int {
+	n := len(substr)
+	for i := 0; i+n <= len(s); i++ {
+		if strings.EqualFold(s[i:i+n], substr) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isTagNameChar(c byte) bool {
+	return isASCIILetter(c) || (c >= '0' && c <= '9') || c == '-' || c == ':' || c == '_'
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// Attr returns the value of the named attribute and whether it was present
+func (n *htmlNode) Attr(key string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
+
+// AttrOr returns the value of the named attribute or def if it is missing
+func (n *htmlNode) AttrOr(key, def string) string {
+	if v, ok := n.Attr(key); ok {
+		return v
+	}
+
+	return def
+}
+
+// HasClass reports whether the element carries the given class name
+func (n *htmlNode) HasClass(class string) bool {
+	classes, ok := n.Attr("class")
+	if !ok {
+		return false
+	}
+
+	for _, c := range strings.Fields(classes) {
+		if c == class {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ElementChildren returns the element children of the node
+func (n *htmlNode) ElementChildren() []*htmlNode {
+	var children []*htmlNode
+	for _, c := range n.Children {
+		if c.Type == elementNode {
+			children = append(children, c)
+		}
+	}
+
+	return children
+}
+
+// Walk visits the node and all its descendants in document order. Returning
+// false from fn skips the descendants of the visited node.
+func (n *htmlNode) Walk(fn func(*htmlNode) bool) {
+	if !fn(n) {
+		return
+	}
+
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// Text returns the whitespace-collapsed text content of the node, skipping
+// scripts, styles and comments
+func (n *htmlNode) Text() string {
+	var sb strings.Builder
+	n.writeText(&sb)
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func (n *htmlNode) writeText(sb *strings.Builder) {
+	switch n.Type {
+	case textNode:
+		sb.WriteString(n.Data)
+	case elementNode, documentNode:
+		if n.Tag == "script" || n.Tag == "style" || n.Tag == "noscript" {
+			return
+		}
+
+		if blockElements[n.Tag] {
+			sb.WriteByte(' ')
+		}
+
+		for _, c := range n.Children {
+			c.writeText(sb)
+		}
+
+		if blockElements[n.Tag] || n.Tag == "br" {
+			sb.WriteByte(' ')
+		}
+	}
+}
+
+// blockElements are separated by whitespace when their text is extracted
+var blockElements = map[string]bool{
+	"p": true, "div": true, "li": true, "ul": true, "ol": true, "tr": true,
+	"td": true, "th": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "section": true, "article": true, "header": true,
+	"footer": true, "blockquote": true, "pre": true, "dd": true, "dt": true,
+	"table": true, "figure": true, "figcaption": true, "nav": true, "aside": true,
+	"main": true,
+}
@@ -1 +1,306 @@
 package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// HtmlParser extracts items from HTML pages using the CSS selectors configured
+// in Source.Selector. The "container" selector picks one element per item and
+// every other selector is evaluated relative to that element. With
+// Source.StructuredData set, the schema.org JSON-LD, microdata and OpenGraph
+// markup within each item is read first and the selectors act as overrides.
+// Source.Extract set to "article" fills the content with the main text of
+// the page, found by scoring its blocks, unless a content selector matches.
+type HtmlParser struct{}
+
+// containerKey is the selector key that identifies one element per item
+const containerKey = "container"
+
+// Parse extracts items from an HTML document
+func (p *HtmlParser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	if content == nil || len(content.Body) == 0 {
+		return nil, fmt.Errorf("no HTML content to parse")
+	}
+
+	if len(source.Selector) == 0 && !source.StructuredData && source.Extract != extractArticle {
+		return nil, fmt.Errorf("HTML parser requires at least one selector")
+	}
+
+	doc := parseHTML(string(content.Body))
+	baseURL := documentBaseURL(doc, content.URL)
+
+	// Compile the field selectors up front so a typo fails the whole source
+	fields, err := compileFieldSelectors(source.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the item containers, or treat the whole page as a single item
+	containers := []*htmlNode{doc}
+	if raw, ok := source.Selector[containerKey]; ok && strings.TrimSpace(raw) != "" {
+		sel, err := compileSelector(raw)
+		if err != nil {
+			return nil, fmt.Errorf("container: %w", err)
+		}
+
+		containers = sel.SelectAll(doc)
+		if len(containers) == 0 {
+			return nil, fmt.Errorf("container selector %q matched no elements: %w", raw, ErrNoContainers)
+		}
+	}
+
+	items := make([]model.Item, 0, len(containers))
+	misses := make(map[string]int)
+
+	for _, container := range containers {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+
+		item := newItem(content, source, "html")
+		var missing []string
+
+		if source.StructuredData {
+			data := extractStructuredData(container, baseURL)
+			data.apply(&item, source)
+		}
+
+		if source.Extract == extractArticle {
+			item.Content = extractArticleText(container)
+			if item.Title == "" {
+				item.Title = articleTitle(container)
+			}
+		}
+
+		for _, field := range fields {
+			value, ok := field.extract(container, baseURL)
+			if !ok {
+				missing = append(missing, field.name)
+				misses[field.name]++
+				continue
+			}
+
+			setItemField(&item, source, field.name, value)
+		}
+
+		if len(missing) > 0 {
+			item.ExtraFields["missing_fields"] = missing
+		}
+
+		// A page parsed as a single item, such as one found in a sitemap, is its own link
+		if container == doc && item.URL == "" {
+			item.URL = content.URL
+		}
+
+		item.ID = itemID(source.Name, firstNonEmpty(item.ID, item.URL), item.Title)
+		items = append(items, item)
+	}
+
+	reportSelectorMisses(source, fields, misses, len(containers))
+
+	return items, nil
+}
+
+// Validate checks that the source configures at least one valid selector
+func (p *HtmlParser) Validate(source *model.Source) error {
+	if source.Extract != "" && source.Extract != extractArticle {
+		return fmt.Errorf("unsupported extract mode '%s' (supported: %s)", source.Extract, extractArticle)
+	}
+
+	if len(source.Selector) == 0 && !source.StructuredData && source.Extract != extractArticle {
+		return fmt.Errorf("HTML parser requires at least one selector")
+	}
+
+	if raw := source.Selector[containerKey]; strings.TrimSpace(raw) != "" {
+		if _, err := compileSelector(raw); err != nil {
+			return fmt.Errorf("container: %w", err)
+		}
+	}
+
+	_, err := compileFieldSelectors(source.Selector)
+	return err
+}
+
+// fieldSelector is a compiled selector for a single item field
+type fieldSelector struct {
+	name string
+	raw  string
+	sel  selector
+	attr string // Attribute to read instead of the text, from a "selector@attr" suffix
+}
+
+// compileFieldSelectors compiles every non-container selector, sorted by field name
+func compileFieldSelectors(selectors map[string]string) ([]fieldSelector, error) {
+	fields := make([]fieldSelector, 0, len(selectors))
+
+	for name, raw := range selectors {
+		if name == containerKey || strings.TrimSpace(raw) == "" {
+			continue
+		}
+
+		expr, attr := splitAttrSuffix(raw)
+		sel, err := compileSelector(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		fields = append(fields, fieldSelector{name: name, raw: raw, sel: sel, attr: attr})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].name < fields[j].name
+	})
+
+	return fields, nil
+}
+
+// splitAttrSuffix splits "a.link@href" into the selector and the attribute name
+func splitAttrSuffix(raw string) (string, string) {
+	i := strings.LastIndexByte(raw, '@')
+	if i < 0 || strings.ContainsAny(raw[i:], "]) ") {
+		return strings.TrimSpace(raw), ""
+	}
+
+	return strings.TrimSpace(raw[:i]), strings.ToLower(strings.TrimSpace(raw[i+1:]))
+}
+
+// extract evaluates the field selector within the container element
+func (f *fieldSelector) extract(container *htmlNode, baseURL string) (string, bool) {
+	el := f.sel.SelectFirst(container)
+	if el == nil {
+		// A selector may also describe the container element itself
+		if container.Type == elementNode && f.sel.Match(container) {
+			el = container
+		} else {
+			return "", false
+		}
+	}
+
+	if f.attr != "" {
+		value, ok := el.Attr(f.attr)
+		if !ok {
+			return "", false
+		}
+
+		if f.attr == "href" || f.attr == "src" {
+			value = resolveURL(baseURL, value)
+		}
+		return strings.TrimSpace(value), true
+	}
+
+	switch f.name {
+	case "url", "link":
+		link := linkTarget(el)
+		if link == "" {
+			return "", false
+		}
+		return resolveURL(baseURL, link), true
+
+	case "image":
+		src := el.AttrOr("src", "")
+		if src == "" {
+			if img := el.findFirst("img"); img != nil {
+				src = img.AttrOr("src", "")
+			}
+		}
+		if src == "" {
+			return "", false
+		}
+		return resolveURL(baseURL, src), true
+
+	case "date":
+		// Prefer machine-readable values over the displayed text
+		for _, key := range []string{"datetime", "content", "title"} {
+			if value, ok := el.Attr(key); ok && strings.TrimSpace(value) != "" {
+				return strings.TrimSpace(value), true
+			}
+		}
+	}
+
+	text := el.Text()
+	if text == "" && el.Tag == "meta" {
+		text = strings.TrimSpace(el.AttrOr("content", ""))
+	}
+
+	return text, text != ""
+}
+
+// articleTitle returns the first heading of an article, or the document title
+func articleTitle(root *htmlNode) string {
+	if h1 := root.findFirst("h1"); h1 != nil {
+		if text := h1.Text(); text != "" {
+			return text
+		}
+	}
+
+	if title := root.findFirst("title"); title != nil {
+		return strings.TrimSpace(title.Text())
+	}
+
+	return ""
+}
+
+// linkTarget returns the href of the element, or of the first link inside it
+func linkTarget(el *htmlNode) string {
+	if href, ok := el.Attr("href"); ok {
+		return strings.TrimSpace(href)
+	}
+
+	if a := el.findFirst("a"); a != nil {
+		return strings.TrimSpace(a.AttrOr("href", ""))
+	}
+
+	return ""
+}
+
+// findFirst returns the first descendant element with the given tag
+func (n *htmlNode) findFirst(tag string) *htmlNode {
+	var found *htmlNode
+
+	for _, c := range n.Children {
+		c.Walk(func(d *htmlNode) bool {
+			if found != nil {
+				return false
+			}
+
+			if d.Type == elementNode && d.Tag == tag {
+				found = d
+				return false
+			}
+			return true
+		})
+
+		if found != nil {
+			break
+		}
+	}
+
+	return found
+}
+
+// documentBaseURL returns the URL that relative links in the document resolve against
+func documentBaseURL(doc *htmlNode, pageURL string) string {
+	if base := doc.findFirst("base"); base != nil {
+		if href := strings.TrimSpace(base.AttrOr("href", "")); href != "" {
+			return resolveURL(pageURL, href)
+		}
+	}
+
+	return pageURL
+}
+
+// reportSelectorMisses logs, per field, how many items a selector failed to match
+func reportSelectorMisses(source *model.Source, fields []fieldSelector, misses map[string]int, total int) {
+	for _, field := range fields {
+		if n := misses[field.name]; n > 0 {
+			log.Printf("HTML parser: selector %q for field '%s' matched nothing in %d of %d items from %s",
+				field.raw, field.name, n, total, source.Name)
+		}
+	}
+}
@@ -1 +1,371 @@
 package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// JsonParser extracts items from JSON documents. The "container" mapping
+// selects the array of items and every other mapping is a path evaluated
+// relative to each element, such as "name", "data.items", "media[0].url" or
+// "tags[*].label".
+type JsonParser struct{}
+
+// Parse extracts items from a JSON document
+func (p *JsonParser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	if content == nil || len(content.Body) == 0 {
+		return nil, fmt.Errorf("no JSON content to parse")
+	}
+
+	doc, err := decodeJSON(content.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := jsonContainer(doc, source.Mapping[containerKey])
+	if err != nil {
+		return nil, err
+	}
+
+	fields := sortedMappingKeys(source.Mapping)
+	items := make([]model.Item, 0, len(elements))
+
+	for _, element := range elements {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+
+		item := newItem(content, source, "json")
+		var missing []string
+
+		for _, field := range fields {
+			value, ok, err := evalJSONPath(element, source.Mapping[field])
+			if err != nil {
+				return nil, fmt.Errorf("mapping '%s': %w", field, err)
+			}
+
+			if !ok || value == nil {
+				missing = append(missing, field)
+				continue
+			}
+
+			if isFeedCoreField(field) {
+				if text := jsonText(value); text != "" {
+					if field == "url" {
+						text = resolveURL(content.URL, text)
+					}
+					setItemField(&item, source, field, text)
+				}
+				continue
+			}
+
+			// Everything else keeps its JSON type
+			item.ExtraFields[field] = value
+		}
+
+		if len(missing) > 0 {
+			item.ExtraFields["missing_fields"] = missing
+		}
+
+		// A mapped id identifies the item, as a guid does in a feed
+		id := jsonText(item.ExtraFields["id"])
+		if id == "" && item.URL == "" && item.Title == "" {
+			// Nothing identifies the element but its values
+			raw, _ := json.Marshal(element)
+			item.ID = itemID(source.Name, string(raw))
+		} else {
+			item.ID = itemID(source.Name, firstNonEmpty(id, item.URL), item.Title)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Validate checks that the source configures at least one valid mapping
+func (p *JsonParser) Validate(source *model.Source) error {
+	if len(source.Mapping) == 0 {
+		return fmt.Errorf("JSON parser requires at least one mapping")
+	}
+
+	for field, path := range source.Mapping {
+		if _, err := compileJSONPath(path); err != nil {
+			return fmt.Errorf("mapping '%s': %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeJSON decodes a document, keeping integers as int64 and other numbers as float64
+func decodeJSON(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	return normalizeJSONNumbers(doc), nil
+}
+
+// normalizeJSONNumbers replaces json.Number values with int64 or float64
+func normalizeJSONNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	case map[string]interface{}:
+		for k, val := range t {
+			t[k] = normalizeJSONNumbers(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = normalizeJSONNumbers(val)
+		}
+	}
+
+	return v
+}
+
+// jsonContainer returns the elements that become items. Without a container
+// path, a top-level array yields one item per element and any other document
+// yields a single item.
+func jsonContainer(doc interface{}, path string) ([]interface{}, error) {
+	value := doc
+	if strings.TrimSpace(path) != "" {
+		var ok bool
+		var err error
+
+		value, ok, err = evalJSONPath(doc, path)
+		if err != nil {
+			return nil, fmt.Errorf("container: %w", err)
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("container path %q matched nothing: %w", path, ErrNoContainers)
+		}
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		return v, nil
+	case nil:
+		return nil, nil
+	default:
+		return []interface{}{v}, nil
+	}
+}
+
+// jsonPathStep is one step in a compiled JSON path
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// compileJSONPath parses a path such as "data.items[0].name", "items[*].id",
+// `meta["dotted.key"]` or "tags.*". A leading "$" or "$." is ignored.
+func compileJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+
+	var steps []jsonPathStep
+	i := 0
+
+	for i < len(path) {
+		switch c := path[i]; c {
+		case '.':
+			i++
+
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in path %q", path)
+			}
+
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in path %q", inner, path)
+				}
+				steps = append(steps, jsonPathStep{index: n, isIndex: true})
+			}
+
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+
+			key := path[i : i+end]
+			i += end
+
+			if key == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: key})
+			}
+		}
+	}
+
+	return steps, nil
+}
+
+// evalJSONPath evaluates a path against a decoded document. A path containing
+// a wildcard returns a slice of every match. The boolean reports whether the
+// path matched anything.
+func evalJSONPath(doc interface{}, path string) (interface{}, bool, error) {
+	steps, err := compileJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasWildcard := false
+	for _, step := range steps {
+		if step.wildcard {
+			hasWildcard = true
+			break
+		}
+	}
+
+	matches := walkJSONPath(doc, steps)
+	if len(matches) == 0 {
+		return nil, false, nil
+	}
+
+	if hasWildcard {
+		return matches, true, nil
+	}
+
+	return matches[0], true, nil
+}
+
+// walkJSONPath returns every value reachable from v by following steps
+func walkJSONPath(v interface{}, steps []jsonPathStep) []interface{} {
+	if len(steps) == 0 {
+		return []interface{}{v}
+	}
+
+	step, rest := steps[0], steps[1:]
+
+	switch {
+	case step.wildcard:
+		var matches []interface{}
+		switch t := v.(type) {
+		case []interface{}:
+			for _, elem := range t {
+				matches = append(matches, walkJSONPath(elem, rest)...)
+			}
+		case map[string]interface{}:
+			for _, key := range sortedKeys(t) {
+				matches = append(matches, walkJSONPath(t[key], rest)...)
+			}
+		}
+		return matches
+
+	case step.isIndex:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+
+		idx := step.index
+		if idx < 0 {
+			idx += len(arr)
+		}
+
+		if idx < 0 || idx >= len(arr) {
+			return nil
+		}
+		return walkJSONPath(arr[idx], rest)
+
+	default:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		child, ok := obj[step.key]
+		if !ok {
+			return nil
+		}
+		return walkJSONPath(child, rest)
+	}
+}
+
+// jsonText renders a JSON value as text for a core item field
+func jsonText(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case []interface{}:
+		parts := make([]string, 0, len(t))
+		for _, elem := range t {
+			if text := jsonText(elem); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, ", ")
+	default:
+		encoded, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprintf("%v", t)
+		}
+		return string(encoded)
+	}
+}
+
+// sortedMappingKeys returns the mapping keys except the container, sorted
+func sortedMappingKeys(mapping map[string]string) []string {
+	keys := make([]string, 0, len(mapping))
+	for key := range mapping {
+		if key != containerKey {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
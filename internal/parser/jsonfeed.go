@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// JsonFeedParser extracts items from JSON Feed 1.0 and 1.1 documents
+// (https://www.jsonfeed.org/version/1.1/). The item fields are mapped
+// directly, so no mappings are needed; any mappings that are configured are
+// evaluated against each item and stored as extra fields.
+type JsonFeedParser struct{}
+
+// Parse extracts items from a JSON Feed document
+func (p *JsonFeedParser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	if content == nil || len(content.Body) == 0 {
+		return nil, fmt.Errorf("no JSON Feed content to parse")
+	}
+
+	doc, err := decodeJSON(content.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("JSON Feed document must be an object")
+	}
+
+	entries, ok := feed["items"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("JSON Feed document has no items array")
+	}
+
+	// Feed-level metadata shared by every item
+	feedMeta := make(map[string]interface{})
+	for key, field := range map[string]string{
+		"title":         "feed_title",
+		"icon":          "feed_icon",
+		"favicon":       "feed_favicon",
+		"home_page_url": "feed_home_page_url",
+	} {
+		if value := jsonString(feed, key); value != "" {
+			if key != "title" {
+				value = resolveURL(content.URL, value)
+			}
+			feedMeta[field] = value
+		}
+	}
+
+	feedAuthors := jsonFeedAuthors(feed)
+	fields := sortedMappingKeys(source.Mapping)
+	items := make([]model.Item, 0, len(entries))
+
+	for _, raw := range entries {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+
+		entry, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		item := newItem(content, source, "jsonfeed")
+		for key, value := range feedMeta {
+			item.ExtraFields[key] = value
+		}
+
+		item.Title = jsonString(entry, "title")
+		item.URL = resolveURL(content.URL, firstNonEmpty(jsonString(entry, "url"), jsonString(entry, "external_url")))
+		item.Content = firstNonEmpty(jsonString(entry, "content_html"), jsonString(entry, "content_text"))
+
+		if date := firstNonEmpty(jsonString(entry, "date_published"), jsonString(entry, "date_modified")); date != "" {
+			setItemField(&item, source, "date", date)
+		}
+
+		// Item authors override the feed authors
+		authors := jsonFeedAuthors(entry)
+		if len(authors) == 0 {
+			authors = feedAuthors
+		}
+		item.Author = strings.Join(authors, ", ")
+
+		if tags := jsonStrings(entry["tags"]); len(tags) > 0 {
+			item.Category = strings.Join(tags, ", ")
+			item.ExtraFields["categories"] = tags
+		}
+
+		for _, key := range []string{"summary", "date_modified", "language", "external_url"} {
+			if value := jsonString(entry, key); value != "" {
+				item.ExtraFields[key] = value
+			}
+		}
+
+		for _, key := range []string{"image", "banner_image"} {
+			if value := jsonString(entry, key); value != "" {
+				item.ExtraFields[key] = resolveURL(content.URL, value)
+			}
+		}
+
+		if enclosures := jsonFeedAttachments(entry, content.URL); len(enclosures) > 0 {
+			item.ExtraFields["enclosures"] = enclosures
+		}
+
+		for _, field := range fields {
+			value, ok, err := evalJSONPath(entry, source.Mapping[field])
+			if err != nil {
+				return nil, fmt.Errorf("mapping '%s': %w", field, err)
+			}
+
+			if ok && value != nil {
+				item.ExtraFields[field] = value
+			}
+		}
+
+		guid := jsonText(entry["id"])
+		if guid != "" {
+			item.ExtraFields["guid"] = guid
+		}
+
+		item.ID = itemID(source.Name, firstNonEmpty(guid, item.URL), item.Title)
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Validate checks that any configured mappings are valid JSON paths
+func (p *JsonFeedParser) Validate(source *model.Source) error {
+	for field, path := range source.Mapping {
+		if _, err := compileJSONPath(path); err != nil {
+			return fmt.Errorf("mapping '%s': %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+// jsonFeedAuthors returns the author names of a feed or item, reading the
+// 1.1 "authors" array and falling back to the 1.0 "author" object
+func jsonFeedAuthors(obj map[string]interface{}) []string {
+	list, _ := obj["authors"].([]interface{})
+
+	if len(list) == 0 && obj["author"] != nil {
+		list = []interface{}{obj["author"]}
+	}
+
+	var names []string
+	for _, raw := range list {
+		author, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if name := firstNonEmpty(jsonString(author, "name"), jsonString(author, "url")); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// jsonFeedAttachments converts item attachments into the enclosure
+// representation used by the feed parsers
+func jsonFeedAttachments(entry map[string]interface{}, baseURL string) []map[string]interface{} {
+	list, _ := entry["attachments"].([]interface{})
+
+	var enclosures []map[string]interface{}
+	for _, raw := range list {
+		attachment, ok := raw.(map[string]interface{})
+		if !ok || jsonString(attachment, "url") == "" {
+			continue
+		}
+
+		enc := enclosure(
+			resolveURL(baseURL, jsonString(attachment, "url")),
+			jsonString(attachment, "mime_type"),
+			jsonText(attachment["size_in_bytes"]),
+		)
+
+		if title := jsonString(attachment, "title"); title != "" {
+			enc["title"] = title
+		}
+
+		if duration := jsonText(attachment["duration_in_seconds"]); duration != "" {
+			enc["duration"] = duration
+		}
+
+		enclosures = append(enclosures, enc)
+	}
+
+	return enclosures
+}
+
+// jsonString returns the trimmed string value of a key, or "" when the key
+// is missing or not a string
+func jsonString(obj map[string]interface{}, key string) string {
+	s, _ := obj[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// jsonStrings returns the non-empty strings of a JSON array
+func jsonStrings(v interface{}) []string {
+	list, _ := v.([]interface{})
+
+	var values []string
+	for _, elem := range list {
+		if s, ok := elem.(string); ok && strings.TrimSpace(s) != "" {
+			values = append(values, strings.TrimSpace(s))
+		}
+	}
+
+	return values
+}
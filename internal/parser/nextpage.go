@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// NextPageURL returns the URL of the page following content according to
+// the source's link_header, html_next or json_cursor pagination strategy,
+// or "" when there is no next page
+func NextPageURL(content *model.Content, source *model.Source) (string, error) {
+	switch strategy := source.PaginationStrategy(); strategy {
+	case model.PaginationLinkHeader:
+		for _, header := range content.Headers.Values("Link") {
+			if next := linkHeaderTarget(header, "next"); next != "" {
+				return resolveURL(content.URL, next), nil
+			}
+		}
+		return "", nil
+
+	case model.PaginationHTMLNext:
+		doc := parseHTML(string(content.Body))
+		sel, err := compileSelector(`a[rel~="next"], link[rel~="next"]`)
+		if err != nil {
+			return "", err
+		}
+
+		for _, el := range sel.SelectAll(doc) {
+			if href := strings.TrimSpace(el.AttrOr("href", "")); href != "" && !strings.HasPrefix(href, "#") {
+				return resolveURL(documentBaseURL(doc, content.URL), href), nil
+			}
+		}
+		return "", nil
+
+	case model.PaginationJSONCursor:
+		doc, err := decodeJSON(content.Body)
+		if err != nil {
+			return "", err
+		}
+
+		value, ok, err := evalJSONPath(doc, source.Pagination.CursorPath)
+		if err != nil {
+			return "", fmt.Errorf("cursor_path: %w", err)
+		}
+
+		// A null, false or empty cursor marks the last page
+		if !ok || value == nil || value == false {
+			return "", nil
+		}
+
+		cursor := jsonText(value)
+		if cursor == "" {
+			return "", nil
+		}
+		return source.GetURLWithCursor(cursor), nil
+
+	default:
+		return "", fmt.Errorf("pagination strategy '%s' has no next-page link", strategy)
+	}
+}
+
+// linkHeaderTarget returns the target of the first link with the given
+// relation in an RFC 5988 Link header, such as `<https://x/?p=2>; rel="next"`
+func linkHeaderTarget(header, rel string) string {
+	for len(header) > 0 {
+		start := strings.IndexByte(header, '<')
+		if start < 0 {
+			return ""
+		}
+
+		end := strings.IndexByte(header[start:], '>')
+		if end < 0 {
+			return ""
+		}
+
+		target := header[start+1 : start+end]
+		header = header[start+end+1:]
+
+		// The parameters run up to the next link
+		params := header
+		if next := strings.IndexByte(header, '<'); next >= 0 {
+			params = header[:next]
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+				continue
+			}
+
+			value = strings.Trim(strings.TrimRight(strings.TrimSpace(value), ", "), `"`)
+			for _, r := range strings.Fields(value) {
+				if strings.EqualFold(r, rel) {
+					return strings.TrimSpace(target)
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -2,91 +2,203 @@ package parser
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/dateparse"
 	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/pkg/config"
 )
 
-type Parser struct {
-	HtmlParser *HtmlParser
-	JsonParser *JsonParser
-	XmlParser  *XmlParser
-	RssParser  *RssParser
+// ErrNoContainers is wrapped by the error of a parse whose item container
+// matched nothing, such as the empty page past the end of a paginated listing
+var ErrNoContainers = errors.New("no items on the page")
 
-	content     string
-	contentType string
-	mu          sync.RWMutex
+// ItemParser extracts items from fetched content. A single instance serves
+// every parse worker, so implementations must be safe for concurrent use.
+type ItemParser interface {
+	Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error)
 }
 
-// GetContent returns the content of the parser
-func (p *Parser) GetContent() string {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return p.content
+// SourceValidator is implemented by parsers that require particular source
+// settings, such as selectors or mappings. It is consulted when sources are
+// validated.
+type SourceValidator interface {
+	Validate(source *model.Source) error
 }
 
-func (p *Parser) GetContentType() string {
-	return p.contentType
+// ParserFunc adapts an ordinary function to the ItemParser interface
+type ParserFunc func(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error)
+
+// Parse calls f(ctx, content, source)
+func (f ParserFunc) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	return f(ctx, content, source)
 }
 
-type HtmlParser struct {
-	// Add fields and methods specific to HTML parsing
+var (
+	registry   = make(map[string]ItemParser)
+	registryMu sync.RWMutex
+)
+
+func init() {
+	Register("html", &HtmlParser{})
+	Register("json", &JsonParser{})
+	Register("xml", &XmlParser{})
+	Register("rss", &RssParser{})
+	Register("jsonfeed", &JsonFeedParser{})
+	Register("csv", &CsvParser{})
+	Register("tsv", &CsvParser{tab: true})
+	Register("auto", &AutoParser{})
 }
 
-type JsonParser struct {
-	// Add fields and methods specific to JSON parsing
+// Register makes a parser available under the given type name, so sources can
+// reference it with "parser: <name>". Registering an existing name replaces
+// the previous parser, which allows the built-in parsers to be overridden.
+func Register(name string, p ItemParser) {
+	if name == "" || p == nil {
+		panic("parser: Register requires a name and a parser")
+	}
+
+	registryMu.Lock()
+	if _, exists := registry[name]; exists {
+		log.Printf("Parser for type '%s' already registered, overwriting", name)
+	}
+	registry[name] = p
+	registryMu.Unlock()
+
+	// Let source validation accept the new type
+	var validate func(*model.Source) error
+	if v, ok := p.(SourceValidator); ok {
+		validate = v.Validate
+	}
+
+	model.RegisterParserType(name, validate)
+	config.RegisterSourceType(name)
 }
 
-type XmlParser struct {
-	// Add fields and methods specific to XML parsing
+// Get returns the parser registered for the specified type
+func Get(parserType string) (ItemParser, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	p, exists := registry[parserType]
+	if !exists {
+		return nil, fmt.Errorf("unknown parser type: %s", parserType)
+	}
+
+	return p, nil
 }
 
-type RssParser struct {
-	// Add fields and methods specific to RSS parsing
+// Types returns the names of all registered parsers, sorted
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for name := range registry {
+		types = append(types, name)
+	}
+
+	sort.Strings(types)
+	return types
 }
 
-// New creates a new Parser instance with the provided parsers.
-func New(htmlParser *HtmlParser, jsonParser *JsonParser, xmlParser *XmlParser, rssParser *RssParser) *Parser {
-	return &Parser{
-		HtmlParser: htmlParser,
-		JsonParser: jsonParser,
-		XmlParser:  xmlParser,
-		RssParser:  rssParser,
+// newItem creates an item carrying the provenance shared by all parsers
+func newItem(content *model.Content, source *model.Source, extractedBy string) model.Item {
+	return model.Item{
+		ExtraFields: make(map[string]interface{}),
+		SourceID:    source.ID,
+		SourceName:  source.Name,
+		SourceURL:   source.URL,
+		FetchedAt:   content.FetchedAt,
+		ParsedAt:    time.Now(),
+		ExtractedBy: extractedBy,
 	}
 }
 
-// Get returns a parser for the specified type.
-func Get(parserType string) (*Parser, error) {
-	switch parserType {
-	case "html":
-		return &Parser{HtmlParser: &HtmlParser{}}, nil
-	case "json":
-		return &Parser{JsonParser: &JsonParser{}}, nil
-	case "xml":
-		return &Parser{XmlParser: &XmlParser{}}, nil
-	case "rss":
-		return &Parser{RssParser: &RssParser{}}, nil
+// setItemField assigns an extracted value to the matching core field of the
+// item, or stores it in ExtraFields when it is not a core field
+func setItemField(item *model.Item, source *model.Source, field, value string) {
+	switch field {
+	case "id":
+		item.ID = value
+	case "title":
+		item.Title = value
+	case "content", "description":
+		if item.Content == "" || field == "content" {
+			item.Content = value
+		}
+	case "url", "link":
+		item.URL = value
+	case "author":
+		item.Author = value
+	case "category":
+		item.Category = value
+	case "date":
+		setItemDate(item, source, value)
 	default:
-		return nil, fmt.Errorf("unknown parser type: %s", parserType)
+		item.ExtraFields[field] = value
 	}
 }
 
-func (c *Parser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
-	switch {
-	case c.HtmlParser != nil:
-		// TODO: Implement HTML parsing logic
-		return nil, fmt.Errorf("HTML parsing not implemented")
-	case c.JsonParser != nil:
-		// TODO: Implement JSON parsing logic
-		return nil, fmt.Errorf("JSON parsing not implemented")
-	case c.XmlParser != nil:
-		// TODO: Implement XML parsing logic
-		return nil, fmt.Errorf("XML parsing not implemented")
-	case c.RssParser != nil:
-		// TODO: Implement RSS parsing logic
-		return nil, fmt.Errorf("RSS parsing not implemented")
-	default:
-		return nil, fmt.Errorf("no parser available")
+// itemID derives a stable identifier for an item from its identifying fields
+func itemID(parts ...string) string {
+	h := sha1.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// resolveURL resolves a possibly relative reference against a base URL
+func resolveURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || base == "" {
+		return ref
+	}
+
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
 	}
+
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// setItemDate parses a raw timestamp with the source's date settings. A value
+// that cannot be parsed leaves Date zero and is flagged in ExtraFields,
+// rather than being replaced by a made-up time.
+func setItemDate(item *model.Item, source *model.Source, raw string) {
+	delete(item.ExtraFields, "raw_date")
+	delete(item.ExtraFields, "date_error")
+
+	opts := dateparse.Options{Format: source.DateFormat}
+	if loc, err := dateparse.LoadLocation(source.Timezone); err == nil {
+		opts.Location = loc
+	}
+
+	date, err := dateparse.Parse(raw, opts)
+	if err != nil {
+		item.Date = time.Time{}
+		item.ExtraFields["raw_date"] = raw
+		item.ExtraFields["date_error"] = err.Error()
+		return
+	}
+
+	item.Date = date
 }
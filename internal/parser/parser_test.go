@@ -0,0 +1,718 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+func TestHtmlParserParse(t *testing.T) {
+	body := `<html><body>
+		<article class="news-item">
+			<h2 class="headline">First &amp; foremost</h2>
+			<div class="article-summary"><p>One<p>Two</div>
+			<time class="publish-date" datetime="2024-03-03T10:00:00Z">Mar 3</time>
+			<a class="read-more" href="/posts/1">Read more</a>
+		</article>
+		<article class="news-item">
+			<h2 class="headline">Second</h2>
+			<a class="read-more" href="posts/2">Read more</a>
+		</article>
+	</body></html>`
+
+	source := &model.Source{
+		Name: "test",
+		URL:  "https://example.com/news/",
+		Selector: map[string]string{
+			"container": "article.news-item",
+			"title":     "h2.headline",
+			"content":   "div.article-summary",
+			"date":      ".publish-date",
+			"url":       "a.read-more",
+		},
+	}
+
+	content := &model.Content{URL: "https://example.com/news/", Body: []byte(body)}
+
+	items, err := (&HtmlParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("Parse() got %d items, want 2", len(items))
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"first title", items[0].Title, "First & foremost"},
+		{"first content", items[0].Content, "One Two"},
+		{"first url", items[0].URL, "https://example.com/posts/1"},
+		{"first date", items[0].Date.Format("2006-01-02"), "2024-03-03"},
+		{"second title", items[1].Title, "Second"},
+		{"second url", items[1].URL, "https://example.com/news/posts/2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+
+	missing, _ := items[1].ExtraFields["missing_fields"].([]string)
+	if len(missing) != 2 || missing[0] != "content" || missing[1] != "date" {
+		t.Errorf("Parse() got missing fields %v, want [content date]", missing)
+	}
+}
+
+func TestHtmlParserContainerMiss(t *testing.T) {
+	source := &model.Source{
+		Name:     "test",
+		Selector: map[string]string{"container": "div.missing", "title": "h1"},
+	}
+
+	content := &model.Content{Body: []byte("<h1>Title</h1>")}
+
+	if _, err := (&HtmlParser{}).Parse(context.Background(), content, source); err == nil {
+		t.Errorf("Parse() expected an error when the container matches nothing")
+	}
+}
+
+func TestRssParserParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantTitle  string
+		wantURL    string
+		wantAuthor string
+		wantDate   string
+	}{
+		{
+			name: "RSS 2.0",
+			body: `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+				<item><title>Hello</title><link>/posts/1</link><dc:creator>Ann</dc:creator>
+				<pubDate>Mon, 2 Jan 2006 15:04:05 GMT</pubDate><guid isPermaLink="false">1</guid>
+				<category>go</category><category>web</category>
+				<enclosure url="https://example.com/a.mp3" type="audio/mpeg" length="10"/></item>
+			</channel></rss>`,
+			wantTitle:  "Hello",
+			wantURL:    "https://example.com/posts/1",
+			wantAuthor: "Ann",
+			wantDate:   "2006-01-02",
+		},
+		{
+			name: "RSS 1.0",
+			body: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"
+				xmlns:dc="http://purl.org/dc/elements/1.1/"><channel rdf:about="https://example.com/"/>
+				<item rdf:about="https://example.com/1"><title>Hello</title><link>https://example.com/1</link>
+				<dc:date>2024-01-02T03:04:05Z</dc:date><dc:creator>Ann</dc:creator></item></rdf:RDF>`,
+			wantTitle:  "Hello",
+			wantURL:    "https://example.com/1",
+			wantAuthor: "Ann",
+			wantDate:   "2024-01-02",
+		},
+		{
+			name: "Atom 1.0",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>urn:1</id><title>Hello</title>
+				<link rel="alternate" href="https://example.com/a"/><published>2024-05-06T07:08:09Z</published>
+				<author><name>Ann</name></author><content type="xhtml"><div><p>Hi</p></div></content></entry></feed>`,
+			wantTitle:  "Hello",
+			wantURL:    "https://example.com/a",
+			wantAuthor: "Ann",
+			wantDate:   "2024-05-06",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &model.Content{URL: "https://example.com/feed", Body: []byte(tt.body)}
+			source := &model.Source{Name: "test", Mapping: map[string]string{"title": "title"}}
+
+			items, err := (&RssParser{}).Parse(context.Background(), content, source)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if len(items) != 1 {
+				t.Fatalf("Parse() got %d items, want 1", len(items))
+			}
+
+			item := items[0]
+			if item.Title != tt.wantTitle {
+				t.Errorf("Parse() got Title = %q, want %q", item.Title, tt.wantTitle)
+			}
+
+			if item.URL != tt.wantURL {
+				t.Errorf("Parse() got URL = %q, want %q", item.URL, tt.wantURL)
+			}
+
+			if item.Author != tt.wantAuthor {
+				t.Errorf("Parse() got Author = %q, want %q", item.Author, tt.wantAuthor)
+			}
+
+			if got := item.Date.Format("2006-01-02"); got != tt.wantDate {
+				t.Errorf("Parse() got Date = %s, want %s", got, tt.wantDate)
+			}
+
+			if tt.name == "RSS 2.0" {
+				if _, ok := item.ExtraFields["enclosures"]; !ok {
+					t.Errorf("Parse() expected enclosures in ExtraFields")
+				}
+
+				if categories, _ := item.ExtraFields["categories"].([]string); len(categories) != 2 {
+					t.Errorf("Parse() got categories %v, want 2", categories)
+				}
+			}
+		})
+	}
+}
+
+func TestRssParserAtomText(t *testing.T) {
+	body := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>urn:1</id>
+		<title type="html">&lt;b&gt;Hi&lt;/b&gt; &amp;amp; bye</title>
+		<summary type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Short <em>summary</em></p></div></summary>
+		</entry></feed>`
+
+	content := &model.Content{URL: "https://example.com/feed", Body: []byte(body)}
+
+	items, err := (&RssParser{}).Parse(context.Background(), content, &model.Source{Name: "test"})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 1 || items[0].Title != "Hi & bye" || items[0].Content != "Short summary" {
+		t.Fatalf("Parse() got %+v, want title %q and content %q", items, "Hi & bye", "Short summary")
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	doc, err := decodeJSON([]byte(`{
+		"data": {"items": [
+			{"name": "A", "price": 9.5, "media": [{"url": "a.png"}], "tags": [{"label": "x"}, {"label": "y"}]},
+			{"name": "B", "price": 10, "meta": {"dotted.key": true}}
+		]}
+	}`))
+	if err != nil {
+		t.Fatalf("decodeJSON() error = %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"data.items[0].name", "A", true},
+		{"$.data.items[1].price", int64(10), true},
+		{"data.items[0].price", 9.5, true},
+		{"data.items[0].media[0].url", "a.png", true},
+		{"data.items[-1].name", "B", true},
+		{`data.items[1].meta["dotted.key"]`, true, true},
+		{"data.items[0].tags[*].label", "x, y", true},
+		{"data.items[*].name", "A, B", true},
+		{"data.items[5].name", nil, false},
+		{"data.missing", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok, err := evalJSONPath(doc, tt.path)
+			if err != nil {
+				t.Fatalf("evalJSONPath() error = %v", err)
+			}
+
+			if ok != tt.wantOK {
+				t.Fatalf("evalJSONPath() got ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if list, isList := got.([]interface{}); isList {
+				got = jsonText(list)
+			}
+
+			if got != tt.want {
+				t.Errorf("evalJSONPath() got %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestJsonParserParse(t *testing.T) {
+	content := &model.Content{
+		URL:  "https://example.com/v1/products",
+		Body: []byte(`{"products": [{"name": "Widget", "price": 9.5, "productUrl": "/p/1", "stock": 3}]}`),
+	}
+
+	source := &model.Source{
+		Name: "test",
+		Mapping: map[string]string{
+			"container": "products",
+			"title":     "name",
+			"url":       "productUrl",
+			"price":     "price",
+			"stock":     "stock",
+		},
+	}
+
+	items, err := (&JsonParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 1 {
+		t.Fatalf("Parse() got %d items, want 1", len(items))
+	}
+
+	if items[0].Title != "Widget" || items[0].URL != "https://example.com/p/1" {
+		t.Errorf("Parse() got Title = %q, URL = %q", items[0].Title, items[0].URL)
+	}
+
+	if price, ok := items[0].ExtraFields["price"].(float64); !ok || price != 9.5 {
+		t.Errorf("Parse() got price = %v (%T), want float64 9.5", items[0].ExtraFields["price"], items[0].ExtraFields["price"])
+	}
+
+	if stock, ok := items[0].ExtraFields["stock"].(int64); !ok || stock != 3 {
+		t.Errorf("Parse() got stock = %v (%T), want int64 3", items[0].ExtraFields["stock"], items[0].ExtraFields["stock"])
+	}
+}
+
+func TestXmlParserParse(t *testing.T) {
+	content := &model.Content{
+		URL: "https://example.gov/notices.xml",
+		Body: []byte(`<?xml version="1.0"?>
+			<gov:notices xmlns:gov="urn:example:gov" xmlns:xl="http://www.w3.org/1999/xlink">
+				<gov:notice id="1" status="open"><headline>Road closed</headline>
+					<link xl:href="/notices/1"/><tag>roads</tag><tag>traffic</tag>
+					<details><issued>2024-02-03</issued></details></gov:notice>
+				<gov:notice id="2" status="archived"><headline>Old notice</headline></gov:notice>
+				<gov:notice id="3" status="open"><headline>Park reopened</headline></gov:notice>
+			</gov:notices>`),
+	}
+
+	source := &model.Source{
+		Name:       "test",
+		Namespaces: map[string]string{"g": "urn:example:gov"},
+		Mapping: map[string]string{
+			"container": "//g:notice[@status='open']",
+			"title":     "normalize-space(headline)",
+			"url":       "link/@xl:href",
+			"category":  "tag",
+			"date":      ".//issued",
+			"notice_id": "@id",
+		},
+	}
+
+	items, err := (&XmlParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("Parse() got %d items, want 2", len(items))
+	}
+
+	first := items[0]
+	if first.Title != "Road closed" {
+		t.Errorf("Parse() got Title = %q, want %q", first.Title, "Road closed")
+	}
+
+	if first.URL != "https://example.gov/notices/1" {
+		t.Errorf("Parse() got URL = %q", first.URL)
+	}
+
+	if first.Category != "roads, traffic" {
+		t.Errorf("Parse() got Category = %q", first.Category)
+	}
+
+	if first.Date.Format("2006-01-02") != "2024-02-03" {
+		t.Errorf("Parse() got Date = %v", first.Date)
+	}
+
+	if id := items[1].ExtraFields["notice_id"]; id != "3" {
+		t.Errorf("Parse() got notice_id = %v, want 3", id)
+	}
+}
+
+func TestMappedIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser ItemParser
+		body   string
+		source *model.Source
+	}{
+		{
+			name:   "json",
+			parser: &JsonParser{},
+			body:   `[{"sku": "A1", "name": "Widget"}, {"sku": "B2", "name": "Widget"}]`,
+			source: &model.Source{Name: "test", Mapping: map[string]string{"id": "sku", "title": "name"}},
+		},
+		{
+			name:   "xml",
+			parser: &XmlParser{},
+			body:   `<products><product sku="A1"><name>Widget</name></product><product sku="B2"><name>Widget</name></product></products>`,
+			source: &model.Source{Name: "test", Mapping: map[string]string{"container": "//product", "id": "@sku", "title": "name"}},
+		},
+		{
+			name:   "html",
+			parser: &HtmlParser{},
+			body:   `<ul><li><span class="sku">A1</span><b>Widget</b></li><li><span class="sku">B2</span><b>Widget</b></li></ul>`,
+			source: &model.Source{Name: "test", Selector: map[string]string{"container": "li", "id": ".sku", "title": "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &model.Content{URL: "https://example.com/products", Body: []byte(tt.body)}
+
+			items, err := tt.parser.Parse(context.Background(), content, tt.source)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			// Items sharing a title and without a URL are told apart by their ids
+			if len(items) != 2 || items[0].ID == items[1].ID {
+				t.Errorf("Parse() got %d items with IDs %v, want 2 distinct IDs", len(items), itemIDs(items))
+			}
+		})
+	}
+
+	// Without an id, URL or title, JSON elements are told apart by their values
+	content := &model.Content{Body: []byte(`[{"price": 1, "stock": 3}, {"price": 2, "stock": 3}]`)}
+	source := &model.Source{Name: "test", Mapping: map[string]string{"price": "price", "stock": "stock"}}
+
+	items, err := (&JsonParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 2 || items[0].ID == items[1].ID {
+		t.Errorf("Parse() got %d anonymous items with IDs %v, want 2 distinct IDs", len(items), itemIDs(items))
+	}
+}
+
+func itemIDs(items []model.Item) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// registerRuns makes the parser type registered by TestRegister unique to
+// each run, as the registry outlives the test
+var registerRuns int
+
+func TestRegister(t *testing.T) {
+	registerRuns++
+	name := fmt.Sprintf("ourcms%d", registerRuns)
+
+	custom := ParserFunc(func(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+		return []model.Item{{Title: "custom"}}, nil
+	})
+
+	source := &model.Source{Name: "cms", URL: "https://example.com", Parser: name}
+	if err := source.Validate(); err == nil {
+		t.Fatalf("Validate() accepted an unregistered parser type")
+	}
+
+	Register(name, custom)
+
+	if err := source.Validate(); err != nil {
+		t.Errorf("Validate() error = %v after registering the parser", err)
+	}
+
+	p, err := Get(name)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	items, err := p.Parse(context.Background(), &model.Content{}, source)
+	if err != nil || len(items) != 1 || items[0].Title != "custom" {
+		t.Errorf("Parse() got %v, %v from the registered parser", items, err)
+	}
+
+	if _, err := Get("missing"); err == nil {
+		t.Errorf("Get() expected an error for an unknown type")
+	}
+}
+
+func TestDetectParser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		url         string
+		body        string
+		want        string
+	}{
+		{"rss content type", "application/rss+xml; charset=utf-8", "https://example.com/feed", "<rss/>", "rss"},
+		{"feed served as text/xml", "text/xml", "https://example.com/feed", "<?xml version=\"1.0\"?>\n<!-- generated -->\n<feed xmlns=\"http://www.w3.org/2005/Atom\"/>", "rss"},
+		{"rdf", "", "https://example.com/index.rdf", "<rdf:RDF></rdf:RDF>", "rss"},
+		{"json with BOM", "", "https://example.com/api", "\xef\xbb\xbf  {\"items\": []}", "json"},
+		{"json served as html", "text/html", "https://example.com/api", "[1, 2]", "json"},
+		{"html doctype", "", "https://example.com/", "<!DOCTYPE html><html></html>", "html"},
+		{"plain xml", "application/xml", "https://example.com/data", "<catalog/>", "xml"},
+		{"url extension", "application/octet-stream", "https://example.com/data.json?x=1", "", "json"},
+		{"json feed content type", "application/feed+json", "https://example.com/feed", "{}", "jsonfeed"},
+		{"json feed served as json", "application/json", "https://example.com/feed", `{"version": "https://jsonfeed.org/version/1.1", "items": []}`, "jsonfeed"},
+		{"unknown", "text/plain", "https://example.com/", "hello", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &model.Content{URL: tt.url, ContentType: tt.contentType, Body: []byte(tt.body)}
+			if got, _ := detectParser(content); got != tt.want {
+				t.Errorf("detectParser() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoParserValidates(t *testing.T) {
+	content := &model.Content{
+		URL:         "https://example.com/api",
+		ContentType: "application/json",
+		Body:        []byte(`[{"name": "Widget"}]`),
+	}
+
+	// JSON without mappings would silently yield nothing
+	if _, err := (&AutoParser{}).Parse(context.Background(), content, &model.Source{Name: "test"}); err == nil {
+		t.Errorf("Parse() expected an error for JSON content without mappings")
+	}
+
+	source := &model.Source{Name: "test", Mapping: map[string]string{"title": "name"}}
+	items, err := (&AutoParser{}).Parse(context.Background(), content, source)
+	if err != nil || len(items) != 1 || items[0].Title != "Widget" {
+		t.Errorf("Parse() = %+v, %v, want the Widget item", items, err)
+	}
+}
+
+func TestJsonFeedParserParse(t *testing.T) {
+	body := `{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Indie Blog",
+		"icon": "/icon.png",
+		"authors": [{"name": "Feed Author"}],
+		"items": [
+			{
+				"id": "1",
+				"url": "/posts/1",
+				"title": "First post",
+				"content_html": "<p>Hello</p>",
+				"content_text": "Hello",
+				"date_published": "2024-02-01T10:00:00Z",
+				"authors": [{"name": "Ann"}, {"name": "Bob"}],
+				"tags": ["go", "feeds"],
+				"image": "/img/1.png",
+				"attachments": [{"url": "/ep1.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 1024}]
+			},
+			{"id": "2", "url": "https://example.com/posts/2", "content_text": "Plain"}
+		]
+	}`
+
+	content := &model.Content{URL: "https://example.com/feed.json", Body: []byte(body)}
+	source := &model.Source{Name: "indie", Parser: "jsonfeed"}
+
+	items, err := (&JsonFeedParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("Parse() returned %d items, want 2", len(items))
+	}
+
+	first := items[0]
+	if first.Title != "First post" || first.Content != "<p>Hello</p>" || first.URL != "https://example.com/posts/1" {
+		t.Errorf("unexpected core fields: %+v", first)
+	}
+
+	if first.Author != "Ann, Bob" || first.Category != "go, feeds" || first.Date.IsZero() {
+		t.Errorf("unexpected author, category or date: %q %q %v", first.Author, first.Category, first.Date)
+	}
+
+	if first.ExtraFields["feed_title"] != "Indie Blog" || first.ExtraFields["feed_icon"] != "https://example.com/icon.png" {
+		t.Errorf("feed metadata not carried along: %v", first.ExtraFields)
+	}
+
+	if first.ExtraFields["image"] != "https://example.com/img/1.png" {
+		t.Errorf("image = %v", first.ExtraFields["image"])
+	}
+
+	enclosures, _ := first.ExtraFields["enclosures"].([]map[string]interface{})
+	if len(enclosures) != 1 || enclosures[0]["url"] != "https://example.com/ep1.mp3" || enclosures[0]["length"] != "1024" {
+		t.Errorf("enclosures = %v", first.ExtraFields["enclosures"])
+	}
+
+	if items[1].Content != "Plain" || items[1].Author != "Feed Author" || items[1].ExtractedBy != "jsonfeed" {
+		t.Errorf("unexpected second item: %+v", items[1])
+	}
+}
+
+func TestLinkHeaderTarget(t *testing.T) {
+	header := `<https://api.example.com/items?page=1>; rel="first", <https://api.example.com/items?page=3>; rel="next last"`
+
+	if got := linkHeaderTarget(header, "next"); got != "https://api.example.com/items?page=3" {
+		t.Errorf("linkHeaderTarget(next) = %q", got)
+	}
+
+	if got := linkHeaderTarget(header, "prev"); got != "" {
+		t.Errorf("linkHeaderTarget(prev) = %q, want no link", got)
+	}
+}
+
+func TestHtmlParserStructuredData(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><head>
+<meta property="og:title" content="OG title">
+<meta property="og:description" content="OG description">
+<meta property="og:image" content="/og.png">
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+	{"@type": "WebSite", "name": "Example Site"},
+	{"@type": "NewsArticle", "headline": "Rates rise again", "datePublished": "2024-05-01T08:00:00Z",
+	 "author": [{"@type": "Person", "name": "Jane Doe"}], "image": {"@type": "ImageObject", "url": "/lead.jpg"}}
+]}
+</script>
+</head><body><h1 class="headline">Selector headline</h1></body></html>`
+
+	content := &model.Content{URL: "https://news.example.com/a/1", Body: []byte(page)}
+	source := &model.Source{Name: "news", Parser: "html", StructuredData: true}
+
+	items, err := (&HtmlParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	item := items[0]
+	if item.Title != "Rates rise again" || item.Author != "Jane Doe" || item.Date.IsZero() {
+		t.Errorf("unexpected fields from JSON-LD: %+v", item)
+	}
+
+	if item.ExtraFields["description"] != "OG description" || item.ExtraFields["type"] != "news" {
+		t.Errorf("unexpected description or type: %v", item.ExtraFields)
+	}
+
+	images, _ := item.ExtraFields["images"].([]string)
+	if len(images) != 1 || images[0] != "https://news.example.com/lead.jpg" {
+		t.Errorf("images = %v", images)
+	}
+
+	// Selectors override the structured data
+	source.Selector = map[string]string{"title": "h1.headline"}
+	items, err = (&HtmlParser{}).Parse(context.Background(), content, source)
+	if err != nil || items[0].Title != "Selector headline" {
+		t.Errorf("selector override: title = %q, err = %v", items[0].Title, err)
+	}
+}
+
+func TestHtmlParserExtractArticle(t *testing.T) {
+	page := `<html><head><title>Site | Story</title></head><body>
+<header class="site-header"><nav><a href="/">Home</a> <a href="/world">World</a> <a href="/tech">Tech</a></nav></header>
+<div id="layout">
+	<div class="sidebar"><ul><li><a href="/a">Most read, story one, with a long link text</a></li><li><a href="/b">Most read, story two</a></li></ul></div>
+	<div class="story-body">
+		<h1>The river returns</h1>
+		<p>After years of drought, the river has returned to the valley, and farmers say the change came almost overnight.</p>
+		<p>Officials, who had warned of another dry season, now expect the reservoirs to be full by the end of the month.</p>
+		<ul class="related"><li><a href="/x">Related: drought maps</a></li><li><a href="/y">Related: farm prices</a></li></ul>
+		<p>Residents gathered on the banks to watch, some of them for the first time in a decade.</p>
+	</div>
+	<div id="comments"><p>First comment, which is long enough to be scored as a paragraph by the extractor.</p></div>
+</div>
+<footer><p>Copyright, all rights reserved, and a long footer paragraph for good measure.</p></footer>
+</body></html>`
+
+	content := &model.Content{URL: "https://news.example.com/river", Body: []byte(page)}
+	source := &model.Source{Name: "news", Parser: "html", Extract: "article"}
+
+	if err := (&HtmlParser{}).Validate(source); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	items, err := (&HtmlParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	got := items[0].Content
+	for _, want := range []string{"After years of drought", "reservoirs to be full", "Residents gathered"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("article content is missing %q:\n%s", want, got)
+		}
+	}
+
+	for _, unwanted := range []string{"Home", "Most read", "Related:", "First comment", "Copyright"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("article content contains boilerplate %q:\n%s", unwanted, got)
+		}
+	}
+
+	if items[0].Title != "The river returns" {
+		t.Errorf("Title = %q", items[0].Title)
+	}
+}
+
+func TestCsvParserParse(t *testing.T) {
+	content := &model.Content{
+		URL: "https://partner.example.com/export.csv",
+		Body: []byte("Partner export, generated nightly\n" +
+			"Headline;Link;Published;Region;Notes\r\n" +
+			"'Rates; held';/news/1;2024-03-01;EU;'said ''no'' again'\r\n" +
+			"\r\n" +
+			"Multi-line;/news/2;2024-03-02;US;'first\nsecond'\r\n"),
+	}
+
+	source := &model.Source{
+		Name: "partner",
+		Mapping: map[string]string{
+			"title": "headline",
+			"url":   "Link",
+			"date":  "2",
+		},
+	}
+	source.CSV.Delimiter = ";"
+	source.CSV.Quote = "'"
+	source.CSV.SkipRows = 1
+
+	items, err := (&CsvParser{}).Parse(context.Background(), content, source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("Parse() got %d items, want 2", len(items))
+	}
+
+	first := items[0]
+	if first.Title != "Rates; held" || first.URL != "https://partner.example.com/news/1" || first.Date.Day() != 1 {
+		t.Errorf("Parse() got Title = %q, URL = %q, Date = %v", first.Title, first.URL, first.Date)
+	}
+
+	if first.ExtraFields["Region"] != "EU" || first.ExtraFields["Notes"] != "said 'no' again" {
+		t.Errorf("Parse() got ExtraFields = %v", first.ExtraFields)
+	}
+
+	if items[1].ExtraFields["Notes"] != "first\nsecond" || items[1].ExtractedBy != "csv" {
+		t.Errorf("Parse() got Notes = %q, ExtractedBy = %q", items[1].ExtraFields["Notes"], items[1].ExtractedBy)
+	}
+
+	// Without a header row, mappings are column indexes
+	tsv := &model.Content{Body: []byte("Widget\t9.50\tin stock\n")}
+	noHeader := &model.Source{Name: "tsv", Mapping: map[string]string{"title": "0"}}
+	noHeader.CSV.NoHeader = true
+
+	items, err = (&CsvParser{tab: true}).Parse(context.Background(), tsv, noHeader)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 1 || items[0].Title != "Widget" || items[0].ExtraFields["column_2"] != "in stock" {
+		t.Errorf("Parse() got %+v", items)
+	}
+}
@@ -0,0 +1,289 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// extractArticle is the value of Source.Extract that enables main-content extraction
+const extractArticle = "article"
+
+var (
+	// unlikelyCandidates match the class or id of boilerplate blocks
+	unlikelyCandidates = regexp.MustCompile(`(?i)\b(ad-|ads\b|advert|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|header|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|tool|widget)`)
+
+	// likelyCandidates rescue blocks that also match unlikelyCandidates
+	likelyCandidates = regexp.MustCompile(`(?i)\b(and|article|body|column|content|entry|hentry|main|page|post|story|text)\b`)
+
+	// positiveWeight and negativeWeight adjust the score of a block by its class and id
+	positiveWeight = regexp.MustCompile(`(?i)(article|body|content|entry|hentry|h-entry|main|page|post|story|text|blog)`)
+	negativeWeight = regexp.MustCompile(`(?i)(comment|com-|contact|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|nav|menu)`)
+)
+
+// boilerplateTags never contain the article body
+var boilerplateTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "nav": true, "footer": true,
+	"aside": true, "form": true, "iframe": true, "button": true, "svg": true,
+	"select": true, "input": true, "textarea": true, "header": true, "menu": true,
+}
+
+// articleExtractor scores the blocks of a document to find its main content
+type articleExtractor struct {
+	scores map[*htmlNode]float64
+}
+
+// extractArticleText returns the main text of the page or container rooted
+// at root, with paragraphs separated by blank lines. Blocks are scored by
+// the amount of paragraph text they hold, adjusted for their tag, their
+// class and id, and the share of their text that sits inside links; the best
+// block and its related siblings are rendered without boilerplate.
+func extractArticleText(root *htmlNode) string {
+	e := &articleExtractor{scores: make(map[*htmlNode]float64)}
+
+	root.Walk(func(n *htmlNode) bool {
+		if n.Type != elementNode {
+			return n.Type == documentNode
+		}
+
+		if isBoilerplate(n) {
+			return false
+		}
+
+		switch n.Tag {
+		case "p", "pre", "td", "blockquote":
+			e.scoreParagraph(n)
+		case "div", "section":
+			// Text directly inside a div counts like a paragraph
+			if !hasBlockChildren(n) {
+				e.scoreParagraph(n)
+			}
+		}
+		return true
+	})
+
+	var top *htmlNode
+	var topScore float64
+
+	for n, score := range e.scores {
+		score *= 1 - linkDensity(n)
+		e.scores[n] = score
+
+		if top == nil || score > topScore || (score == topScore && n.depth() < top.depth()) {
+			top, topScore = n, score
+		}
+	}
+
+	if top == nil {
+		// No paragraphs at all: fall back to the text of the whole root
+		return strings.TrimSpace(renderArticle([]*htmlNode{root}))
+	}
+
+	// Related content often sits in sibling blocks of the best candidate
+	blocks := []*htmlNode{top}
+	if parent := top.Parent; parent != nil {
+		threshold := topScore * 0.2
+		if threshold < 10 {
+			threshold = 10
+		}
+
+		blocks = blocks[:0]
+		for _, sibling := range parent.ElementChildren() {
+			switch {
+			case sibling == top:
+				blocks = append(blocks, sibling)
+			case isBoilerplate(sibling):
+			case e.scores[sibling] >= threshold:
+				blocks = append(blocks, sibling)
+			case sibling.Tag == "p":
+				text := sibling.Text()
+				if density := linkDensity(sibling); len(text) > 80 && density < 0.25 ||
+					len(text) > 0 && len(text) <= 80 && density == 0 && strings.Contains(text, ". ") {
+					blocks = append(blocks, sibling)
+				}
+			}
+		}
+	}
+
+	return strings.TrimSpace(renderArticle(blocks))
+}
+
+// scoreParagraph credits the parent and grandparent of a block of text
+func (e *articleExtractor) scoreParagraph(n *htmlNode) {
+	text := n.Text()
+	if len(text) < 25 {
+		return
+	}
+
+	score := 1 + float64(strings.Count(text, ",")) + float64(min(len(text)/100, 3))
+
+	if parent := n.Parent; parent != nil && parent.Type == elementNode {
+		e.initCandidate(parent)
+		e.scores[parent] += score
+
+		if grand := parent.Parent; grand != nil && grand.Type == elementNode {
+			e.initCandidate(grand)
+			e.scores[grand] += score / 2
+		}
+	}
+}
+
+// initCandidate gives a block its starting score from its tag, class and id
+func (e *articleExtractor) initCandidate(n *htmlNode) {
+	if _, ok := e.scores[n]; ok {
+		return
+	}
+
+	var score float64
+	switch n.Tag {
+	case "article", "main":
+		score = 10
+	case "div", "section":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+
+	e.scores[n] = score + classWeight(n)
+}
+
+// classWeight scores the class and id of an element
+func classWeight(n *htmlNode) float64 {
+	var weight float64
+	for _, value := range []string{n.AttrOr("class", ""), n.AttrOr("id", "")} {
+		if value == "" {
+			continue
+		}
+
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+	}
+
+	return weight
+}
+
+// isBoilerplate reports whether an element is navigation, comments or other
+// page furniture that never belongs to the article
+func isBoilerplate(n *htmlNode) bool {
+	if boilerplateTags[n.Tag] {
+		return true
+	}
+
+	if role := n.AttrOr("role", ""); role == "navigation" || role == "complementary" || role == "banner" || role == "contentinfo" {
+		return true
+	}
+
+	if _, hidden := n.Attr("hidden"); hidden {
+		return true
+	}
+
+	match := n.AttrOr("class", "") + " " + n.AttrOr("id", "")
+	if strings.TrimSpace(match) == "" || n.Tag == "body" || n.Tag == "article" || n.Tag == "main" {
+		return false
+	}
+
+	return unlikelyCandidates.MatchString(match) && !likelyCandidates.MatchString(match)
+}
+
+// hasBlockChildren reports whether an element contains block-level elements
+func hasBlockChildren(n *htmlNode) bool {
+	for _, c := range n.ElementChildren() {
+		if blockElements[c.Tag] || c.Tag == "img" || c.Tag == "table" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// linkDensity returns the share of an element's text that is link text
+func linkDensity(n *htmlNode) float64 {
+	total := len(n.Text())
+	if total == 0 {
+		return 0
+	}
+
+	var linked int
+	n.Walk(func(d *htmlNode) bool {
+		if d.Type == elementNode && d.Tag == "a" {
+			linked += len(d.Text())
+			return false
+		}
+		return true
+	})
+
+	return float64(linked) / float64(total)
+}
+
+// depth returns the number of ancestors of the node
+func (n *htmlNode) depth() int {
+	d := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		d++
+	}
+
+	return d
+}
+
+// renderArticle renders the text of the blocks, one paragraph per block-level
+// element, skipping boilerplate and link-heavy lists
+func renderArticle(blocks []*htmlNode) string {
+	var paragraphs []string
+	var inline strings.Builder
+
+	flush := func() {
+		if text := strings.Join(strings.Fields(inline.String()), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		inline.Reset()
+	}
+
+	var render func(n *htmlNode)
+	render = func(n *htmlNode) {
+		switch n.Type {
+		case textNode:
+			inline.WriteString(n.Data)
+			return
+		case elementNode:
+			if isBoilerplate(n) {
+				return
+			}
+
+			// Lists and blocks that are mostly links are menus or related-article boxes
+			if (blockElements[n.Tag] || n.Tag == "ul" || n.Tag == "ol") && n.Tag != "p" && linkDensity(n) > 0.5 {
+				return
+			}
+		case documentNode:
+		default:
+			return
+		}
+
+		block := blockElements[n.Tag] || n.Tag == "br"
+		if block {
+			flush()
+		}
+
+		for _, c := range n.Children {
+			render(c)
+		}
+
+		if block {
+			flush()
+		}
+	}
+
+	for _, block := range blocks {
+		render(block)
+		flush()
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,402 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// RssParser extracts items from RSS 0.9x/2.0, RSS 1.0 (RDF) and Atom 1.0 feeds.
+// Source.Mapping can point any core field at a different element; fields
+// without a mapping, or whose mapped element is missing, use the defaults of
+// the detected feed format.
+type RssParser struct{}
+
+// feedFormat identifies the flavour of a syndication feed
+type feedFormat string
+
+const (
+	feedRSS  feedFormat = "rss"
+	feedRDF  feedFormat = "rdf"
+	feedAtom feedFormat = "atom"
+)
+
+// feedDefaults lists, per format and field, the element paths tried in order
+// when the source does not map the field or its mapping matches nothing
+var feedDefaults = map[feedFormat]map[string][]string{
+	feedRSS: {
+		"title":    {"title"},
+		"content":  {"content:encoded", "description", "summary"},
+		"date":     {"pubDate", "dc:date", "published", "updated"},
+		"author":   {"author", "dc:creator", "itunes:author"},
+		"category": {"category", "dc:subject"},
+		"url":      {"link", "guid"},
+	},
+	feedRDF: {
+		"title":    {"title"},
+		"content":  {"content:encoded", "description"},
+		"date":     {"dc:date", "pubDate"},
+		"author":   {"dc:creator", "author"},
+		"category": {"dc:subject", "category"},
+		"url":      {"link", "@rdf:about"},
+	},
+	feedAtom: {
+		"title":    {"title"},
+		"content":  {"content", "summary"},
+		"date":     {"published", "updated", "issued", "modified"},
+		"author":   {"author/name", "author/email", "dc:creator"},
+		"category": {"category@term", "category"},
+		"url":      {"link"},
+	},
+}
+
+// feedCoreFields are extracted in this order for every feed item
+var feedCoreFields = []string{"title", "content", "date", "author", "category", "url"}
+
+// Parse extracts items from a syndication feed
+func (p *RssParser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	if content == nil || len(content.Body) == 0 {
+		return nil, fmt.Errorf("no feed content to parse")
+	}
+
+	doc, err := parseXMLTree(content.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	root := doc.Root()
+	format, entries, err := feedEntries(root)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.Item, 0, len(entries))
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+
+		item := newItem(content, source, "rss")
+
+		for _, field := range feedCoreFields {
+			paths := feedDefaults[format][field]
+			if mapped, ok := source.Mapping[field]; ok && mapped != "" {
+				paths = append([]string{mapped}, paths...)
+			}
+
+			if value := feedValue(entry, format, field, paths, content.URL); value != "" {
+				setItemField(&item, source, field, value)
+			}
+		}
+
+		// Mappings for non-core fields go straight into the extra fields
+		for field, path := range source.Mapping {
+			if isFeedCoreField(field) || field == containerKey {
+				continue
+			}
+
+			if value := feedValue(entry, format, field, []string{path}, content.URL); value != "" {
+				item.ExtraFields[field] = value
+			}
+		}
+
+		addFeedExtras(&item, entry, format, content.URL)
+
+		guid, _ := item.ExtraFields["guid"].(string)
+		item.ID = itemID(source.Name, firstNonEmpty(guid, item.URL), item.Title)
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// feedEntries detects the feed format and returns its item elements
+func feedEntries(root *xmlNode) (feedFormat, []*xmlNode, error) {
+	if root == nil {
+		return "", nil, fmt.Errorf("empty feed document")
+	}
+
+	switch {
+	case root.Is("rss"):
+		channel := root.Child("channel")
+		if channel == nil {
+			return "", nil, fmt.Errorf("RSS feed has no channel element")
+		}
+		return feedRSS, channel.ChildrenNamed("item"), nil
+
+	case root.Local == "RDF":
+		// RSS 1.0 items are siblings of the channel, but some feeds nest them
+		entries := root.ChildrenNamed("item")
+		if channel := root.Child("channel"); channel != nil {
+			entries = append(entries, channel.ChildrenNamed("item")...)
+		}
+		return feedRDF, entries, nil
+
+	case root.Is("feed"):
+		return feedAtom, root.ChildrenNamed("entry"), nil
+
+	case root.Is("channel"):
+		// Bare channel documents without the rss wrapper
+		return feedRSS, root.ChildrenNamed("item"), nil
+	}
+
+	return "", nil, fmt.Errorf("unrecognised feed root element <%s>", root.QName())
+}
+
+// feedValue returns the first non-empty value found at any of the paths
+func feedValue(entry *xmlNode, format feedFormat, field string, paths []string, baseURL string) string {
+	for _, path := range paths {
+		var value string
+
+		switch {
+		case field == "url" && format == feedAtom && path == "link":
+			value = atomLink(entry, "alternate")
+		case field == "url" && path == "guid":
+			// A guid is only a link when it is a permalink
+			if guid := entry.Child("guid"); guid != nil && guid.AttrOr("isPermaLink", "true") != "false" {
+				value = guid.Text()
+			}
+		case format == feedAtom && (field == "title" || path == "summary"):
+			// Titles and summaries are plain text, whatever their type
+			if el := resolveFeedPath(entry, path); el != nil {
+				value = atomPlainText(el)
+			} else {
+				value = feedPathValue(entry, path)
+			}
+		case field == "content" && format == feedAtom:
+			if el := resolveFeedPath(entry, path); el != nil {
+				value = atomText(el)
+			} else {
+				value = feedPathValue(entry, path)
+			}
+		case field == "category":
+			value = strings.Join(feedCategories(entry, path), ", ")
+		default:
+			value = feedPathValue(entry, path)
+		}
+
+		if value = strings.TrimSpace(value); value != "" {
+			if field == "url" {
+				value = resolveURL(baseURL, value)
+			}
+			return value
+		}
+	}
+
+	return ""
+}
+
+// feedPathValue evaluates a mapping path: "name", "prefix:name", "a/b",
+// "name@attr" or "@attr" (an attribute of the entry itself)
+func feedPathValue(entry *xmlNode, path string) string {
+	path, attr, hasAttr := strings.Cut(path, "@")
+
+	el := entry
+	if path != "" {
+		el = resolveFeedPath(entry, path)
+	}
+
+	if el == nil {
+		return ""
+	}
+
+	if hasAttr {
+		value, _ := el.Attr(attr)
+		return value
+	}
+
+	return el.Text()
+}
+
+// resolveFeedPath follows a slash-separated path of child element names
+func resolveFeedPath(entry *xmlNode, path string) *xmlNode {
+	el := entry
+	for _, step := range strings.Split(strings.Trim(path, "/"), "/") {
+		if el = el.Child(step); el == nil {
+			return nil
+		}
+	}
+
+	return el
+}
+
+// feedCategories returns every value found at a category path
+func feedCategories(entry *xmlNode, path string) []string {
+	path, attr, hasAttr := strings.Cut(path, "@")
+	parentPath, name := "", path
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		parentPath, name = path[:i], path[i+1:]
+	}
+
+	parent := entry
+	if parentPath != "" {
+		if parent = resolveFeedPath(entry, parentPath); parent == nil {
+			return nil
+		}
+	}
+
+	var categories []string
+	for _, el := range parent.ChildrenNamed(name) {
+		value := el.Text()
+		if hasAttr {
+			value, _ = el.Attr(attr)
+		} else if value == "" {
+			// Atom categories carry their value in the term attribute
+			value = el.AttrOr("term", "")
+		}
+
+		if value = strings.TrimSpace(value); value != "" {
+			categories = append(categories, value)
+		}
+	}
+
+	return categories
+}
+
+// atomLink returns the href of the first link with the given rel; a link
+// without a rel attribute counts as "alternate"
+func atomLink(entry *xmlNode, rel string) string {
+	for _, link := range entry.ChildrenNamed("link") {
+		if link.AttrOr("rel", "alternate") == rel {
+			if href := link.AttrOr("href", ""); href != "" {
+				return href
+			}
+		}
+	}
+
+	// RSS-style links inside Atom documents
+	if rel == "alternate" {
+		if link := entry.Child("link"); link != nil {
+			return link.Text()
+		}
+	}
+
+	return ""
+}
+
+// atomText returns the content of an Atom text construct
+func atomText(el *xmlNode) string {
+	if el.AttrOr("type", "text") == "xhtml" {
+		// XHTML content is wrapped in a single div that is not part of it
+		if div := el.Child("div"); div != nil {
+			return div.InnerXML()
+		}
+		return el.InnerXML()
+	}
+
+	return el.Text()
+}
+
+// atomPlainText returns the text of an Atom text construct without the markup
+// of type="html" and type="xhtml" text
+func atomPlainText(el *xmlNode) string {
+	switch el.AttrOr("type", "text") {
+	case "html", "xhtml":
+		return parseHTML(atomText(el)).Text()
+	}
+
+	return el.Text()
+}
+
+// addFeedExtras records enclosures, GUIDs and multiple categories
+func addFeedExtras(item *model.Item, entry *xmlNode, format feedFormat, baseURL string) {
+	var enclosures []map[string]interface{}
+
+	if format == feedAtom {
+		if id := entry.Child("id"); id != nil && id.Text() != "" {
+			item.ExtraFields["guid"] = id.Text()
+		}
+
+		for _, link := range entry.ChildrenNamed("link") {
+			if link.AttrOr("rel", "") != "enclosure" {
+				continue
+			}
+
+			enclosures = append(enclosures, enclosure(
+				resolveURL(baseURL, link.AttrOr("href", "")),
+				link.AttrOr("type", ""),
+				link.AttrOr("length", ""),
+			))
+		}
+	} else {
+		if guid := entry.Child("guid"); guid != nil && guid.Text() != "" {
+			item.ExtraFields["guid"] = guid.Text()
+		} else if about, ok := entry.Attr("rdf:about"); ok && about != "" {
+			item.ExtraFields["guid"] = about
+		}
+
+		for _, el := range entry.ChildrenNamed("enclosure") {
+			enclosures = append(enclosures, enclosure(
+				resolveURL(baseURL, el.AttrOr("url", "")),
+				el.AttrOr("type", ""),
+				el.AttrOr("length", ""),
+			))
+		}
+	}
+
+	// Media RSS attachments are common in both flavours
+	for _, el := range entry.ChildrenNamed("media:content") {
+		if u := el.AttrOr("url", ""); u != "" {
+			enclosures = append(enclosures, enclosure(resolveURL(baseURL, u), el.AttrOr("type", ""), el.AttrOr("fileSize", "")))
+		}
+	}
+
+	if len(enclosures) > 0 {
+		item.ExtraFields["enclosures"] = enclosures
+	}
+
+	path := "category"
+	if format == feedRDF {
+		path = "dc:subject"
+	}
+
+	categories := feedCategories(entry, path)
+	if len(categories) == 0 && format == feedRSS {
+		categories = feedCategories(entry, "dc:subject")
+	}
+
+	if len(categories) > 0 {
+		item.ExtraFields["categories"] = categories
+		if item.Category == "" {
+			item.Category = categories[0]
+		}
+	}
+}
+
+// enclosure builds the extra-field representation of an attached file
+func enclosure(href, mediaType, length string) map[string]interface{} {
+	enc := map[string]interface{}{"url": href}
+	if mediaType != "" {
+		enc["type"] = mediaType
+	}
+
+	if length != "" {
+		enc["length"] = length
+	}
+
+	return enc
+}
+
+func isFeedCoreField(field string) bool {
+	for _, f := range feedCoreFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstNonEmpty returns the first of its arguments that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
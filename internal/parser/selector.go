@@ -0,0 +1,647 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// selector is a compiled group of CSS selectors ("a, b > c")
+type selector []complexSelector
+
+// complexSelector is a chain of compound selectors joined by combinators
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte // combinators[i] joins parts[i] and parts[i+1]: ' ', '>', '+' or '~'
+}
+
+// compoundSelector is a sequence of simple selectors applying to one element
+type compoundSelector struct {
+	tag     string // Empty or "*" matches any element
+	ids     []string
+	classes []string
+	attrs   []attrSelector
+	pseudos []pseudoSelector
+}
+
+// attrSelector matches an attribute, optionally against a value
+type attrSelector struct {
+	key string
+	op  string // "", "=", "~=", "|=", "^=", "$=", "*="
+	val string
+}
+
+// pseudoSelector matches a structural or content pseudo-class
+type pseudoSelector struct {
+	name string
+	a, b int      // For the nth-* family: matches positions a*n+b
+	not  selector // For :not()
+	text string   // For :contains()
+}
+
+// selectorCache holds compiled selectors, keyed by their source text
+var selectorCache sync.Map
+
+// compileSelector parses a CSS selector group, caching the result
+func compileSelector(src string) (selector, error) {
+	if cached, ok := selectorCache.Load(src); ok {
+		return cached.(selector), nil
+	}
+
+	p := &selectorParser{src: src}
+	sel, err := p.parseGroup()
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", src, err)
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("invalid selector %q: unexpected %q at offset %d", src, p.src[p.pos], p.pos)
+	}
+
+	selectorCache.Store(src, sel)
+	return sel, nil
+}
+
+// selectorParser is a small recursive-descent parser for CSS selectors
+type selectorParser struct {
+	src string
+	pos int
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+
+	return p.pos > start
+}
+
+func (p *selectorParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+
+	return 0
+}
+
+func (p *selectorParser) parseGroup() (selector, error) {
+	var group selector
+
+	for {
+		p.skipSpace()
+		cs, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, cs)
+
+		p.skipSpace()
+		if p.peek() != ',' {
+			return group, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var cs complexSelector
+
+	compound, err := p.parseCompound()
+	if err != nil {
+		return cs, err
+	}
+	cs.parts = append(cs.parts, compound)
+
+	for {
+		hadSpace := p.skipSpace()
+
+		c := p.peek()
+		var combinator byte
+		switch {
+		case c == '>' || c == '+' || c == '~':
+			combinator = c
+			p.pos++
+			p.skipSpace()
+		case c == 0 || c == ',' || c == ')':
+			return cs, nil
+		case hadSpace:
+			combinator = ' '
+		default:
+			return cs, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+		}
+
+		compound, err := p.parseCompound()
+		if err != nil {
+			return cs, err
+		}
+
+		cs.combinators = append(cs.combinators, combinator)
+		cs.parts = append(cs.parts, compound)
+	}
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var cs compoundSelector
+	start := p.pos
+
+	if p.peek() == '*' {
+		cs.tag = "*"
+		p.pos++
+	} else if name := p.parseIdent(); name != "" {
+		cs.tag = strings.ToLower(name)
+	}
+
+	for {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			id := p.parseIdent()
+			if id == "" {
+				return cs, fmt.Errorf("expected id after '#' at offset %d", p.pos)
+			}
+			cs.ids = append(cs.ids, id)
+
+		case '.':
+			p.pos++
+			class := p.parseIdent()
+			if class == "" {
+				return cs, fmt.Errorf("expected class name after '.' at offset %d", p.pos)
+			}
+			cs.classes = append(cs.classes, class)
+
+		case '[':
+			p.pos++
+			attr, err := p.parseAttr()
+			if err != nil {
+				return cs, err
+			}
+			cs.attrs = append(cs.attrs, attr)
+
+		case ':':
+			p.pos++
+			// Tolerate pseudo-elements written with a double colon
+			if p.peek() == ':' {
+				p.pos++
+			}
+			pseudo, err := p.parsePseudo()
+			if err != nil {
+				return cs, err
+			}
+			cs.pseudos = append(cs.pseudos, pseudo)
+
+		default:
+			if p.pos == start {
+				return cs, fmt.Errorf("expected selector at offset %d", p.pos)
+			}
+			return cs, nil
+		}
+	}
+}
+
+func (p *selectorParser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if isTagNameChar(c) && c != ':' || c >= 0x80 {
+			p.pos++
+			continue
+		}
+
+		if c == '\\' && p.pos+1 < len(p.src) {
+			p.pos += 2
+			continue
+		}
+		break
+	}
+
+	return strings.ReplaceAll(p.src[start:p.pos], "\\", "")
+}
+
+func (p *selectorParser) parseAttr() (attrSelector, error) {
+	var attr attrSelector
+
+	p.skipSpace()
+	attr.key = strings.ToLower(p.parseIdent())
+	if attr.key == "" {
+		return attr, fmt.Errorf("expected attribute name at offset %d", p.pos)
+	}
+	p.skipSpace()
+
+	if p.peek() == ']' {
+		p.pos++
+		return attr, nil
+	}
+
+	for _, op := range []string{"~=", "|=", "^=", "$=", "*=", "="} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			attr.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+
+	if attr.op == "" {
+		return attr, fmt.Errorf("expected attribute operator at offset %d", p.pos)
+	}
+
+	p.skipSpace()
+	if c := p.peek(); c == '"' || c == '\'' {
+		p.pos++
+		end := strings.IndexByte(p.src[p.pos:], c)
+		if end < 0 {
+			return attr, fmt.Errorf("unterminated attribute value")
+		}
+		attr.val = p.src[p.pos : p.pos+end]
+		p.pos += end + 1
+	} else {
+		attr.val = p.parseIdent()
+	}
+
+	p.skipSpace()
+	if p.peek() != ']' {
+		return attr, fmt.Errorf("expected ']' at offset %d", p.pos)
+	}
+	p.pos++
+
+	return attr, nil
+}
+
+func (p *selectorParser) parsePseudo() (pseudoSelector, error) {
+	ps := pseudoSelector{name: strings.ToLower(p.parseIdent())}
+
+	switch ps.name {
+	case "first-child", "last-child", "only-child", "first-of-type", "last-of-type", "only-of-type", "empty":
+		return ps, nil
+
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		arg, err := p.parseArgument()
+		if err != nil {
+			return ps, err
+		}
+		ps.a, ps.b, err = parseNth(arg)
+		return ps, err
+
+	case "not":
+		if p.peek() != '(' {
+			return ps, fmt.Errorf("expected '(' after :not")
+		}
+		p.pos++
+		inner, err := p.parseGroup()
+		if err != nil {
+			return ps, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return ps, fmt.Errorf("expected ')' at offset %d", p.pos)
+		}
+		p.pos++
+		ps.not = inner
+		return ps, nil
+
+	case "contains":
+		arg, err := p.parseArgument()
+		if err != nil {
+			return ps, err
+		}
+		ps.text = strings.Trim(strings.TrimSpace(arg), `"'`)
+		return ps, nil
+
+	default:
+		return ps, fmt.Errorf("unsupported pseudo-class :%s", ps.name)
+	}
+}
+
+// parseArgument reads a parenthesised pseudo-class argument
+func (p *selectorParser) parseArgument() (string, error) {
+	if p.peek() != '(' {
+		return "", fmt.Errorf("expected '(' at offset %d", p.pos)
+	}
+
+	end := strings.IndexByte(p.src[p.pos:], ')')
+	if end < 0 {
+		return "", fmt.Errorf("unterminated pseudo-class argument")
+	}
+
+	arg := p.src[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+
+	return arg, nil
+}
+
+// parseNth parses the an+b micro-syntax used by :nth-child and friends
+func parseNth(arg string) (int, int, error) {
+	arg = strings.ToLower(strings.ReplaceAll(arg, " ", ""))
+
+	switch arg {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+
+	n := strings.IndexByte(arg, 'n')
+	if n < 0 {
+		b, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", arg)
+		}
+		return 0, b, nil
+	}
+
+	var a int
+	switch coef := arg[:n]; coef {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(coef); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", arg)
+		}
+	}
+
+	b := 0
+	if rest := arg[n+1:]; rest != "" {
+		var err error
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", arg)
+		}
+	}
+
+	return a, b, nil
+}
+
+// Match reports whether the element matches any selector in the group
+func (s selector) Match(n *htmlNode) bool {
+	if n == nil || n.Type != elementNode {
+		return false
+	}
+
+	for i := range s {
+		if s[i].match(n, len(s[i].parts)-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SelectAll returns the descendants of root matching the selector, in document order
+func (s selector) SelectAll(root *htmlNode) []*htmlNode {
+	var matches []*htmlNode
+
+	for _, c := range root.Children {
+		c.Walk(func(n *htmlNode) bool {
+			if s.Match(n) {
+				matches = append(matches, n)
+			}
+			return true
+		})
+	}
+
+	return matches
+}
+
+// SelectFirst returns the first descendant of root matching the selector
+func (s selector) SelectFirst(root *htmlNode) *htmlNode {
+	var found *htmlNode
+
+	for _, c := range root.Children {
+		c.Walk(func(n *htmlNode) bool {
+			if found != nil {
+				return false
+			}
+
+			if s.Match(n) {
+				found = n
+				return false
+			}
+			return true
+		})
+
+		if found != nil {
+			break
+		}
+	}
+
+	return found
+}
+
+// match checks parts[idx] against n and the remaining parts against n's relatives
+func (cs *complexSelector) match(n *htmlNode, idx int) bool {
+	if !cs.parts[idx].match(n) {
+		return false
+	}
+
+	if idx == 0 {
+		return true
+	}
+
+	switch cs.combinators[idx-1] {
+	case ' ':
+		for a := n.Parent; a != nil && a.Type == elementNode; a = a.Parent {
+			if cs.match(a, idx-1) {
+				return true
+			}
+		}
+	case '>':
+		if a := n.Parent; a != nil && a.Type == elementNode {
+			return cs.match(a, idx-1)
+		}
+	case '+':
+		if prev := previousElement(n); prev != nil {
+			return cs.match(prev, idx-1)
+		}
+	case '~':
+		for prev := previousElement(n); prev != nil; prev = previousElement(prev) {
+			if cs.match(prev, idx-1) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *compoundSelector) match(n *htmlNode) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != n.Tag {
+		return false
+	}
+
+	for _, id := range c.ids {
+		if n.AttrOr("id", "") != id {
+			return false
+		}
+	}
+
+	for _, class := range c.classes {
+		if !n.HasClass(class) {
+			return false
+		}
+	}
+
+	for _, attr := range c.attrs {
+		if !attr.match(n) {
+			return false
+		}
+	}
+
+	for i := range c.pseudos {
+		if !c.pseudos[i].match(n) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *attrSelector) match(n *htmlNode) bool {
+	val, ok := n.Attr(a.key)
+	if !ok {
+		return false
+	}
+
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return val == a.val
+	case "~=":
+		for _, f := range strings.Fields(val) {
+			if f == a.val {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return val == a.val || strings.HasPrefix(val, a.val+"-")
+	case "^=":
+		return a.val != "" && strings.HasPrefix(val, a.val)
+	case "$=":
+		return a.val != "" && strings.HasSuffix(val, a.val)
+	case "*=":
+		return a.val != "" && strings.Contains(val, a.val)
+	}
+
+	return false
+}
+
+func (ps *pseudoSelector) match(n *htmlNode) bool {
+	switch ps.name {
+	case "first-child":
+		return previousElement(n) == nil
+	case "last-child":
+		return nextElement(n) == nil
+	case "only-child":
+		return previousElement(n) == nil && nextElement(n) == nil
+	case "first-of-type":
+		return siblingPosition(n, true, false) == 1
+	case "last-of-type":
+		return siblingPosition(n, true, true) == 1
+	case "only-of-type":
+		return siblingPosition(n, true, false) == 1 && siblingPosition(n, true, true) == 1
+	case "nth-child":
+		return nthMatches(ps.a, ps.b, siblingPosition(n, false, false))
+	case "nth-last-child":
+		return nthMatches(ps.a, ps.b, siblingPosition(n, false, true))
+	case "nth-of-type":
+		return nthMatches(ps.a, ps.b, siblingPosition(n, true, false))
+	case "nth-last-of-type":
+		return nthMatches(ps.a, ps.b, siblingPosition(n, true, true))
+	case "not":
+		return !ps.not.Match(n)
+	case "contains":
+		return strings.Contains(n.Text(), ps.text)
+	case "empty":
+		for _, c := range n.Children {
+			if c.Type == elementNode || (c.Type == textNode && c.Data != "") {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// nthMatches reports whether pos equals a*k+b for some k >= 0
+func nthMatches(a, b, pos int) bool {
+	if a == 0 {
+		return pos == b
+	}
+
+	diff := pos - b
+	return diff%a == 0 && diff/a >= 0
+}
+
+// siblingPosition returns the 1-based position of n among its element
+// siblings, optionally counting only siblings of the same type or from the end
+func siblingPosition(n *htmlNode, sameType, fromEnd bool) int {
+	if n.Parent == nil {
+		return 1
+	}
+
+	siblings := n.Parent.Children
+	pos := 0
+
+	for i := range siblings {
+		s := siblings[i]
+		if fromEnd {
+			s = siblings[len(siblings)-1-i]
+		}
+
+		if s.Type != elementNode || (sameType && s.Tag != n.Tag) {
+			continue
+		}
+
+		pos++
+		if s == n {
+			return pos
+		}
+	}
+
+	return pos
+}
+
+func previousElement(n *htmlNode) *htmlNode {
+	if n.Parent == nil {
+		return nil
+	}
+
+	var prev *htmlNode
+	for _, s := range n.Parent.Children {
+		if s == n {
+			return prev
+		}
+
+		if s.Type == elementNode {
+			prev = s
+		}
+	}
+
+	return nil
+}
+
+func nextElement(n *htmlNode) *htmlNode {
+	if n.Parent == nil {
+		return nil
+	}
+
+	found := false
+	for _, s := range n.Parent.Children {
+		if s == n {
+			found = true
+			continue
+		}
+
+		if found && s.Type == elementNode {
+			return s
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,478 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// structuredData holds the fields found in the schema.org JSON-LD,
+// microdata and OpenGraph markup of a page
+type structuredData struct {
+	SchemaType  string // @type, itemtype or og:type as found
+	Title       string
+	Description string
+	Author      string
+	Date        string
+	URL         string
+	Images      []string
+}
+
+// extractStructuredData reads the structured markup within root. JSON-LD is
+// preferred over microdata, which is preferred over OpenGraph and plain meta
+// tags; each source only fills the fields the previous ones left empty.
+// Objects describing page furniture, such as WebSite or Organization, are
+// only consulted after the meta tags.
+func extractStructuredData(root *htmlNode, baseURL string) structuredData {
+	var data structuredData
+
+	jsonLD, jsonLDOther := jsonLDNodes(root)
+	microdata, microdataOther := microdataItems(root)
+
+	for _, node := range jsonLD {
+		data.merge(jsonLDData(node, baseURL))
+	}
+
+	for _, item := range microdata {
+		data.merge(microdataData(item, baseURL))
+	}
+
+	data.merge(metaTagData(root, baseURL))
+
+	for _, node := range jsonLDOther {
+		data.merge(jsonLDData(node, baseURL))
+	}
+
+	for _, item := range microdataOther {
+		data.merge(microdataData(item, baseURL))
+	}
+
+	return data
+}
+
+// merge fills the empty fields of d from other
+func (d *structuredData) merge(other structuredData) {
+	if d.SchemaType == "" {
+		d.SchemaType = other.SchemaType
+	}
+	if d.Title == "" {
+		d.Title = other.Title
+	}
+	if d.Description == "" {
+		d.Description = other.Description
+	}
+	if d.Author == "" {
+		d.Author = other.Author
+	}
+	if d.Date == "" {
+		d.Date = other.Date
+	}
+	if d.URL == "" {
+		d.URL = other.URL
+	}
+	if len(d.Images) == 0 {
+		d.Images = other.Images
+	}
+}
+
+// apply copies the structured data onto an item. The description, images
+// and result type are kept in the extra fields for the result conversion.
+func (d *structuredData) apply(item *model.Item, source *model.Source) {
+	if d.Title != "" {
+		item.Title = d.Title
+	}
+	if d.Author != "" {
+		item.Author = d.Author
+	}
+	if d.URL != "" {
+		item.URL = d.URL
+	}
+	if d.Date != "" {
+		setItemField(item, source, "date", d.Date)
+	}
+	if d.Description != "" {
+		item.ExtraFields["description"] = d.Description
+	}
+	if len(d.Images) > 0 {
+		item.ExtraFields["images"] = d.Images
+	}
+	if d.SchemaType != "" {
+		item.ExtraFields["schema_type"] = d.SchemaType
+		item.ExtraFields["type"] = string(resultTypeFor(d.SchemaType))
+	}
+}
+
+// resultTypeFor maps a schema.org or OpenGraph type to a result type
+func resultTypeFor(schemaType string) model.ResultType {
+	t := schemaType
+	if i := strings.LastIndexAny(t, "/#"); i >= 0 {
+		t = t[i+1:] // https://schema.org/NewsArticle
+	}
+	t = strings.ToLower(t)
+
+	switch {
+	case strings.Contains(t, "newsarticle"), t == "reportage", t == "liveblogposting":
+		return model.ResultTypeNews
+	case strings.Contains(t, "blogposting"), t == "blog", t == "socialmediaposting":
+		return model.ResultTypeBlogPost
+	case strings.HasSuffix(t, "article"):
+		return model.ResultTypeArticle
+	case t == "product", t == "individualproduct", t == "productmodel", t == "offer", t == "product.item":
+		return model.ResultTypeProduct
+	case strings.HasSuffix(t, "event"):
+		return model.ResultTypeEvent
+	case strings.HasSuffix(t, "object"), t == "movie", t == "podcastepisode",
+		strings.HasPrefix(t, "video."), strings.HasPrefix(t, "music."):
+		return model.ResultTypeMedia
+	}
+
+	return model.ResultTypeOther
+}
+
+// jsonLDNodes returns the schema.org objects of every JSON-LD block, split
+// into content types such as articles and products and everything else
+func jsonLDNodes(root *htmlNode) (primary, other []map[string]interface{}) {
+	root.Walk(func(n *htmlNode) bool {
+		if n.Type != elementNode || n.Tag != "script" {
+			return true
+		}
+
+		if !strings.EqualFold(strings.TrimSpace(n.AttrOr("type", "")), "application/ld+json") {
+			return false
+		}
+
+		var sb strings.Builder
+		for _, c := range n.Children {
+			sb.WriteString(c.Data)
+		}
+
+		doc, err := decodeJSON([]byte(sb.String()))
+		if err != nil {
+			return false
+		}
+
+		for _, node := range flattenJSONLD(doc) {
+			if resultTypeFor(jsonLDType(node)) != model.ResultTypeOther {
+				primary = append(primary, node)
+			} else {
+				other = append(other, node)
+			}
+		}
+		return false
+	})
+
+	return primary, other
+}
+
+// flattenJSONLD returns the typed objects of a JSON-LD document, unwrapping
+// top-level arrays and @graph collections
+func flattenJSONLD(v interface{}) []map[string]interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		var nodes []map[string]interface{}
+		for _, elem := range t {
+			nodes = append(nodes, flattenJSONLD(elem)...)
+		}
+		return nodes
+	case map[string]interface{}:
+		if graph, ok := t["@graph"]; ok {
+			return flattenJSONLD(graph)
+		}
+		if _, ok := t["@type"]; ok {
+			return []map[string]interface{}{t}
+		}
+	}
+
+	return nil
+}
+
+// jsonLDType returns the first @type of a JSON-LD object
+func jsonLDType(node map[string]interface{}) string {
+	switch t := node["@type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, elem := range t {
+			if s, ok := elem.(string); ok {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+// jsonLDData reads the fields of one JSON-LD object
+func jsonLDData(node map[string]interface{}, baseURL string) structuredData {
+	data := structuredData{
+		SchemaType:  jsonLDType(node),
+		Title:       firstNonEmpty(jsonLDText(node["headline"]), jsonLDText(node["name"])),
+		Description: jsonLDText(node["description"]),
+		Author:      strings.Join(jsonLDNames(node["author"]), ", "),
+		URL:         jsonLDText(node["url"]),
+	}
+
+	for _, key := range []string{"datePublished", "startDate", "dateCreated", "uploadDate", "dateModified"} {
+		if data.Date = jsonLDText(node[key]); data.Date != "" {
+			break
+		}
+	}
+
+	if data.Author == "" {
+		data.Author = strings.Join(jsonLDNames(node["creator"]), ", ")
+	}
+
+	if data.URL != "" {
+		data.URL = resolveURL(baseURL, data.URL)
+	}
+
+	for _, img := range jsonLDURLs(node["image"]) {
+		data.Images = append(data.Images, resolveURL(baseURL, img))
+	}
+
+	if len(data.Images) == 0 {
+		for _, img := range jsonLDURLs(node["thumbnailUrl"]) {
+			data.Images = append(data.Images, resolveURL(baseURL, img))
+		}
+	}
+
+	return data
+}
+
+// jsonLDText returns a scalar JSON-LD value, or the @value of a value object
+func jsonLDText(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case map[string]interface{}:
+		return jsonLDText(t["@value"])
+	case []interface{}:
+		if len(t) > 0 {
+			return jsonLDText(t[0])
+		}
+	}
+
+	return ""
+}
+
+// jsonLDNames returns the names of people or organizations, given as
+// strings, objects with a name or arrays of either
+func jsonLDNames(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		if s := strings.TrimSpace(t); s != "" {
+			return []string{s}
+		}
+	case map[string]interface{}:
+		if name := jsonLDText(t["name"]); name != "" {
+			return []string{name}
+		}
+	case []interface{}:
+		var names []string
+		for _, elem := range t {
+			names = append(names, jsonLDNames(elem)...)
+		}
+		return names
+	}
+
+	return nil
+}
+
+// jsonLDURLs returns image URLs given as strings, ImageObjects or arrays of either
+func jsonLDURLs(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		if s := strings.TrimSpace(t); s != "" {
+			return []string{s}
+		}
+	case map[string]interface{}:
+		if u := firstNonEmpty(jsonLDText(t["url"]), jsonLDText(t["contentUrl"])); u != "" {
+			return []string{u}
+		}
+	case []interface{}:
+		var urls []string
+		for _, elem := range t {
+			urls = append(urls, jsonLDURLs(elem)...)
+		}
+		return urls
+	}
+
+	return nil
+}
+
+// microdataItems returns the top-level microdata items within root, split
+// into content types and everything else
+func microdataItems(root *htmlNode) (primary, other []*htmlNode) {
+	root.Walk(func(n *htmlNode) bool {
+		if n.Type != elementNode {
+			return true
+		}
+
+		if _, scoped := n.Attr("itemscope"); !scoped {
+			return true
+		}
+
+		if _, isProp := n.Attr("itemprop"); isProp {
+			return true
+		}
+
+		if resultTypeFor(n.AttrOr("itemtype", "")) != model.ResultTypeOther {
+			primary = append(primary, n)
+		} else {
+			other = append(other, n)
+		}
+		return false
+	})
+
+	return primary, other
+}
+
+// microdataProps returns the elements holding the properties of an item,
+// excluding those that belong to nested items
+func microdataProps(item *htmlNode) map[string][]*htmlNode {
+	props := make(map[string][]*htmlNode)
+
+	for _, c := range item.Children {
+		c.Walk(func(n *htmlNode) bool {
+			if n.Type != elementNode {
+				return false
+			}
+
+			for _, name := range strings.Fields(n.AttrOr("itemprop", "")) {
+				props[name] = append(props[name], n)
+			}
+
+			// Properties inside a nested item belong to that item
+			_, scoped := n.Attr("itemscope")
+			return !scoped
+		})
+	}
+
+	return props
+}
+
+// microdataValue returns the value of a property element following the
+// microdata rules; a nested item yields its name
+func microdataValue(el *htmlNode, baseURL string) string {
+	if _, scoped := el.Attr("itemscope"); scoped {
+		if names := microdataProps(el)["name"]; len(names) > 0 {
+			return microdataValue(names[0], baseURL)
+		}
+		return ""
+	}
+
+	var value string
+	switch el.Tag {
+	case "meta":
+		value = el.AttrOr("content", "")
+	case "img", "audio", "video", "source", "embed", "iframe", "track":
+		value = resolveURL(baseURL, el.AttrOr("src", ""))
+	case "a", "link", "area":
+		value = resolveURL(baseURL, el.AttrOr("href", ""))
+	case "object":
+		value = resolveURL(baseURL, el.AttrOr("data", ""))
+	case "time":
+		value = el.AttrOr("datetime", "")
+	case "data", "meter":
+		value = el.AttrOr("value", "")
+	}
+
+	if strings.TrimSpace(value) == "" {
+		value = firstNonEmpty(el.AttrOr("content", ""), el.Text())
+	}
+
+	return strings.TrimSpace(value)
+}
+
+// microdataData reads the fields of one microdata item
+func microdataData(item *htmlNode, baseURL string) structuredData {
+	props := microdataProps(item)
+	first := func(names ...string) string {
+		for _, name := range names {
+			for _, el := range props[name] {
+				if value := microdataValue(el, baseURL); value != "" {
+					return value
+				}
+			}
+		}
+		return ""
+	}
+
+	data := structuredData{
+		SchemaType:  item.AttrOr("itemtype", ""),
+		Title:       first("headline", "name"),
+		Description: first("description"),
+		Date:        first("datePublished", "startDate", "dateCreated", "uploadDate", "dateModified"),
+		URL:         first("url"),
+	}
+
+	var authors []string
+	for _, el := range props["author"] {
+		if name := microdataValue(el, baseURL); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	data.Author = strings.Join(authors, ", ")
+
+	for _, el := range props["image"] {
+		if src := microdataValue(el, baseURL); src != "" {
+			data.Images = append(data.Images, src)
+		}
+	}
+
+	return data
+}
+
+// metaTagData reads OpenGraph, article and Twitter card meta tags, with the
+// plain description and author meta tags as fallbacks
+func metaTagData(root *htmlNode, baseURL string) structuredData {
+	meta := make(map[string][]string)
+
+	root.Walk(func(n *htmlNode) bool {
+		if n.Type != elementNode || n.Tag != "meta" {
+			return true
+		}
+
+		key := strings.ToLower(firstNonEmpty(n.AttrOr("property", ""), n.AttrOr("name", "")))
+		if value := strings.TrimSpace(n.AttrOr("content", "")); key != "" && value != "" {
+			meta[key] = append(meta[key], value)
+		}
+		return false
+	})
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if values := meta[key]; len(values) > 0 {
+				return values[0]
+			}
+		}
+		return ""
+	}
+
+	data := structuredData{
+		SchemaType:  first("og:type"),
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		Author:      first("article:author", "author", "twitter:creator"),
+		Date:        first("article:published_time", "og:published_time", "date", "dc.date"),
+		URL:         first("og:url"),
+	}
+
+	if data.URL != "" {
+		data.URL = resolveURL(baseURL, data.URL)
+	}
+
+	images := meta["og:image"]
+	if len(images) == 0 {
+		images = meta["og:image:url"]
+	}
+	if len(images) == 0 {
+		images = meta["twitter:image"]
+	}
+
+	for _, img := range images {
+		data.Images = append(data.Images, resolveURL(baseURL, img))
+	}
+
+	return data
+}
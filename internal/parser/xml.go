@@ -1 +1,126 @@
-package parser
\ No newline at end of file
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/CyberwizD/Concurrent-Web-Content-Aggregator/internal/model"
+)
+
+// XmlParser extracts items from arbitrary XML documents using XPath
+// expressions. The "container" mapping is evaluated against the document and
+// selects one node per item; every other mapping is evaluated relative to
+// that node. Prefixes used in the expressions can be bound to namespace URIs
+// through Source.Namespaces.
+type XmlParser struct{}
+
+// Parse extracts items from an XML document
+func (p *XmlParser) Parse(ctx context.Context, content *model.Content, source *model.Source) ([]model.Item, error) {
+	if content == nil || len(content.Body) == 0 {
+		return nil, fmt.Errorf("no XML content to parse")
+	}
+
+	doc, err := parseXMLTree(content.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile every mapping up front so a typo fails the whole source
+	fields := sortedMappingKeys(source.Mapping)
+	exprs := make(map[string]xpathExpr, len(fields))
+	for _, field := range fields {
+		expr, err := compileXPath(source.Mapping[field])
+		if err != nil {
+			return nil, fmt.Errorf("mapping '%s': %w", field, err)
+		}
+		exprs[field] = expr
+	}
+
+	// Without a container, the document element is the only item
+	containers := []xpathItem{{node: doc.Root()}}
+	if path := strings.TrimSpace(source.Mapping[containerKey]); path != "" {
+		expr, err := compileXPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("container: %w", err)
+		}
+
+		containers = evalXPath(expr, doc, source.Namespaces)
+		if len(containers) == 0 {
+			return nil, fmt.Errorf("container path %q matched no nodes: %w", path, ErrNoContainers)
+		}
+	}
+
+	items := make([]model.Item, 0, len(containers))
+
+	for _, container := range containers {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+
+		item := newItem(content, source, "xml")
+		var missing []string
+
+		for _, field := range fields {
+			values := xpathValues(evalXPath(exprs[field], container.node, source.Namespaces))
+			if len(values) == 0 {
+				missing = append(missing, field)
+				continue
+			}
+
+			switch {
+			case field == "category":
+				setItemField(&item, source, field, strings.Join(values, ", "))
+				if len(values) > 1 {
+					item.ExtraFields["categories"] = values
+				}
+			case field == "url":
+				setItemField(&item, source, field, resolveURL(content.URL, values[0]))
+			case isFeedCoreField(field):
+				setItemField(&item, source, field, values[0])
+			case len(values) == 1:
+				item.ExtraFields[field] = values[0]
+			default:
+				item.ExtraFields[field] = values
+			}
+		}
+
+		if len(missing) > 0 {
+			item.ExtraFields["missing_fields"] = missing
+		}
+
+		// A mapped id identifies the item, as a guid does in a feed
+		id, _ := item.ExtraFields["id"].(string)
+		item.ID = itemID(source.Name, firstNonEmpty(id, item.URL), item.Title)
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Validate checks that the source configures at least one valid XPath mapping
+func (p *XmlParser) Validate(source *model.Source) error {
+	if len(source.Mapping) == 0 {
+		return fmt.Errorf("XML parser requires at least one mapping")
+	}
+
+	for field, path := range source.Mapping {
+		if _, err := compileXPath(path); err != nil {
+			return fmt.Errorf("mapping '%s': %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+// xpathValues returns the non-empty, trimmed string values of a node set
+func xpathValues(items []xpathItem) []string {
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if value := strings.TrimSpace(item.String()); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
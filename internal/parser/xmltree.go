@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlNode is an element or a run of character data in a parsed XML document
+type xmlNode struct {
+	Prefix   string // Namespace prefix as written in the document
+	Space    string // Namespace URI the prefix resolves to
+	Local    string // Local name, empty for character data
+	Attrs    []xmlAttr
+	Data     string // Character data, for text nodes
+	Parent   *xmlNode
+	Children []*xmlNode
+}
+
+// xmlAttr is an attribute of an XML element
+type xmlAttr struct {
+	Prefix string
+	Space  string
+	Local  string
+	Value  string
+}
+
+// parseXMLTree decodes an XML document into a tree. The decoder runs in
+// non-strict mode so the common mistakes found in real-world feeds (HTML
+// entities, unclosed tags, unquoted attributes) do not abort the parse.
+func parseXMLTree(body []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	// Bodies are handed to the parsers as UTF-8, whatever the prolog declares
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	root := &xmlNode{}
+	current := root
+	scopes := []map[string]string{{"xml": "http://www.w3.org/XML/1998/namespace"}}
+
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			// Keep whatever was parsed before the error if it contains elements
+			if len(root.Elements()) > 0 {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := make(map[string]string)
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					scope[a.Name.Local] = a.Value
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					scope[""] = a.Value
+				}
+			}
+			scopes = append(scopes, scope)
+
+			el := &xmlNode{
+				Prefix: t.Name.Space,
+				Space:  lookupNamespace(scopes, t.Name.Space),
+				Local:  t.Name.Local,
+				Parent: current,
+			}
+
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+
+				attr := xmlAttr{Prefix: a.Name.Space, Local: a.Name.Local, Value: a.Value}
+				if a.Name.Space != "" {
+					attr.Space = lookupNamespace(scopes, a.Name.Space)
+				}
+				el.Attrs = append(el.Attrs, attr)
+			}
+
+			current.Children = append(current.Children, el)
+			current = el
+
+		case xml.EndElement:
+			// Pop to the matching element, ignoring stray end tags
+			for n := current; n != root; n = n.Parent {
+				if n.Local == t.Name.Local && n.Prefix == t.Name.Space {
+					for c := current; c != n.Parent; c = c.Parent {
+						scopes = scopes[:len(scopes)-1]
+					}
+					current = n.Parent
+					break
+				}
+			}
+
+		case xml.CharData:
+			current.appendText(string(t))
+		}
+	}
+
+	if len(root.Elements()) == 0 {
+		return nil, fmt.Errorf("failed to parse XML: no root element")
+	}
+
+	return root, nil
+}
+
+// lookupNamespace resolves a prefix against the stack of namespace scopes
+func lookupNamespace(scopes []map[string]string, prefix string) string {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if uri, ok := scopes[i][prefix]; ok {
+			return uri
+		}
+	}
+
+	return ""
+}
+
+func (n *xmlNode) appendText(text string) {
+	if k := len(n.Children); k > 0 && n.Children[k-1].Local == "" {
+		n.Children[k-1].Data += text
+		return
+	}
+
+	n.Children = append(n.Children, &xmlNode{Data: text, Parent: n})
+}
+
+// IsElement reports whether the node is an element rather than character data
+func (n *xmlNode) IsElement() bool {
+	return n.Local != ""
+}
+
+// QName returns the element name as written in the document, with its prefix
+func (n *xmlNode) QName() string {
+	if n.Prefix == "" {
+		return n.Local
+	}
+
+	return n.Prefix + ":" + n.Local
+}
+
+// Root returns the document element
+func (n *xmlNode) Root() *xmlNode {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+
+	if elements := n.Elements(); len(elements) > 0 {
+		return elements[0]
+	}
+
+	return nil
+}
+
+// Elements returns the element children of the node
+func (n *xmlNode) Elements() []*xmlNode {
+	var elements []*xmlNode
+	for _, c := range n.Children {
+		if c.IsElement() {
+			elements = append(elements, c)
+		}
+	}
+
+	return elements
+}
+
+// Is reports whether the element has the given name. A prefixed name matches
+// on the document prefix; an unprefixed name matches the local name only.
+func (n *xmlNode) Is(name string) bool {
+	if !n.IsElement() {
+		return false
+	}
+
+	if prefix, local, ok := strings.Cut(name, ":"); ok {
+		return strings.EqualFold(n.Local, local) && n.Prefix == prefix
+	}
+
+	return strings.EqualFold(n.Local, name)
+}
+
+// Child returns the first child element with the given name
+func (n *xmlNode) Child(name string) *xmlNode {
+	for _, c := range n.Children {
+		if c.Is(name) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// ChildrenNamed returns every child element with the given name
+func (n *xmlNode) ChildrenNamed(name string) []*xmlNode {
+	var matches []*xmlNode
+	for _, c := range n.Children {
+		if c.Is(name) {
+			matches = append(matches, c)
+		}
+	}
+
+	return matches
+}
+
+// Descendants returns every descendant element with the given name, in document order
+func (n *xmlNode) Descendants(name string) []*xmlNode {
+	var matches []*xmlNode
+	for _, c := range n.Children {
+		if c.Is(name) {
+			matches = append(matches, c)
+		}
+
+		if c.IsElement() {
+			matches = append(matches, c.Descendants(name)...)
+		}
+	}
+
+	return matches
+}
+
+// Attr returns the value of an attribute, matched by local name or prefix:local
+func (n *xmlNode) Attr(name string) (string, bool) {
+	prefix, local, prefixed := strings.Cut(name, ":")
+	if !prefixed {
+		local = name
+	}
+
+	for _, a := range n.Attrs {
+		if a.Local == local && (!prefixed || a.Prefix == prefix) {
+			return a.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// AttrOr returns the value of the named attribute or def if it is missing
+func (n *xmlNode) AttrOr(name, def string) string {
+	if value, ok := n.Attr(name); ok {
+		return value
+	}
+
+	return def
+}
+
+// Text returns the trimmed text content of the node and its descendants
+func (n *xmlNode) Text() string {
+	var sb strings.Builder
+	n.writeText(&sb)
+
+	return strings.TrimSpace(sb.String())
+}
+
+func (n *xmlNode) writeText(sb *strings.Builder) {
+	if !n.IsElement() {
+		sb.WriteString(n.Data)
+		return
+	}
+
+	for _, c := range n.Children {
+		c.writeText(sb)
+	}
+}
+
+// InnerXML re-serializes the content of the element, used for embedded XHTML
+func (n *xmlNode) InnerXML() string {
+	var sb strings.Builder
+	for _, c := range n.Children {
+		c.writeXML(&sb)
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+func (n *xmlNode) writeXML(sb *strings.Builder) {
+	if !n.IsElement() {
+		xml.EscapeText(sb, []byte(n.Data))
+		return
+	}
+
+	// Embedded markup is rendered without prefixes, as browsers expect for XHTML
+	sb.WriteString("<" + n.Local)
+	for _, a := range n.Attrs {
+		sb.WriteString(" " + a.Local + `="`)
+		xml.EscapeText(sb, []byte(a.Value))
+		sb.WriteString(`"`)
+	}
+
+	if len(n.Children) == 0 {
+		sb.WriteString("/>")
+		return
+	}
+
+	sb.WriteString(">")
+	for _, c := range n.Children {
+		c.writeXML(sb)
+	}
+	sb.WriteString("</" + n.Local + ">")
+}
@@ -0,0 +1,1163 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// This file implements the subset of XPath 1.0 used by the XML parser:
+// location paths with the child, descendant, parent, ancestor, sibling, self
+// and attribute axes (including the "//", ".", ".." and "@" abbreviations),
+// name, "*", "prefix:*", text() and node() tests, predicates with positions,
+// comparisons, "and"/"or", unions, and the common string functions.
+
+// xpathItem is a node in an XPath result: an element, a text node, the
+// document, or an attribute together with the element that owns it
+type xpathItem struct {
+	node *xmlNode
+	attr *xmlAttr
+}
+
+// String returns the XPath string value of the item
+func (it xpathItem) String() string {
+	if it.attr != nil {
+		return it.attr.Value
+	}
+
+	if !it.node.IsElement() && it.node.Parent != nil {
+		return it.node.Data
+	}
+
+	return it.node.Text()
+}
+
+// xpathContext is the evaluation context of an expression
+type xpathContext struct {
+	item       xpathItem
+	pos, size  int
+	namespaces map[string]string // Prefix -> URI declared by the source, optional
+}
+
+// xpathExpr is a compiled XPath expression. Evaluation yields a []xpathItem,
+// a string, a float64 or a bool.
+type xpathExpr interface {
+	eval(ctx *xpathContext) interface{}
+}
+
+// xpathCache holds compiled expressions, keyed by their source text
+var xpathCache sync.Map
+
+// compileXPath parses an XPath expression, caching the result
+func compileXPath(src string) (xpathExpr, error) {
+	if cached, ok := xpathCache.Load(src); ok {
+		return cached.(xpathExpr), nil
+	}
+
+	tokens, err := lexXPath(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath %q: %w", src, err)
+	}
+
+	p := &xpathParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath %q: %w", src, err)
+	}
+
+	if !p.done() {
+		return nil, fmt.Errorf("invalid XPath %q: unexpected %q", src, p.peek().text)
+	}
+
+	xpathCache.Store(src, expr)
+	return expr, nil
+}
+
+// evalXPath evaluates an expression against a node. Scalar results are
+// returned as a single-element string slice so callers can treat every
+// result as a list of values.
+func evalXPath(expr xpathExpr, node *xmlNode, namespaces map[string]string) []xpathItem {
+	ctx := &xpathContext{item: xpathItem{node: node}, pos: 1, size: 1, namespaces: namespaces}
+
+	switch v := expr.eval(ctx).(type) {
+	case []xpathItem:
+		return v
+	default:
+		text := xpathString(v)
+		if text == "" {
+			return nil
+		}
+		return []xpathItem{{node: &xmlNode{Data: text, Parent: node}}}
+	}
+}
+
+// Lexer
+
+type xpathTokenKind int
+
+const (
+	tokName xpathTokenKind = iota
+	tokString
+	tokNumber
+	tokOp
+)
+
+type xpathToken struct {
+	kind xpathTokenKind
+	text string
+}
+
+func lexXPath(src string) ([]xpathToken, error) {
+	var tokens []xpathToken
+	i := 0
+
+	for i < len(src) {
+		c := src[i]
+
+		switch {
+		case isSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			tokens = append(tokens, xpathToken{tokString, src[i+1 : i+1+end]})
+			i += end + 2
+
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9'):
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, xpathToken{tokNumber, src[start:i]})
+
+		case isASCIILetter(c) || c == '_':
+			start := i
+			for i < len(src) && (isTagNameChar(src[i]) && src[i] != ':' || src[i] == '.') {
+				i++
+			}
+
+			// A single colon joins a prefix to a local name or "*"
+			if i+1 < len(src) && src[i] == ':' && src[i+1] != ':' {
+				i++
+				if src[i] == '*' {
+					i++
+				} else {
+					for i < len(src) && (isTagNameChar(src[i]) && src[i] != ':' || src[i] == '.') {
+						i++
+					}
+				}
+			}
+			tokens = append(tokens, xpathToken{tokName, src[start:i]})
+
+		default:
+			op := ""
+			for _, candidate := range []string{"//", "::", "..", "!=", "<=", ">=", "/", "[", "]", "(", ")", "@", ",", "|", ".", "*", "=", "<", ">", "-", "+"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, xpathToken{tokOp, op})
+			i += len(op)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parser
+
+type xpathParser struct {
+	tokens []xpathToken
+	pos    int
+}
+
+func (p *xpathParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *xpathParser) peek() xpathToken {
+	if p.done() {
+		return xpathToken{kind: tokOp}
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *xpathParser) peekAt(offset int) xpathToken {
+	if p.pos+offset >= len(p.tokens) {
+		return xpathToken{kind: tokOp}
+	}
+
+	return p.tokens[p.pos+offset]
+}
+
+func (p *xpathParser) isOp(text string) bool {
+	t := p.peek()
+	return !p.done() && t.kind == tokOp && t.text == text
+}
+
+func (p *xpathParser) isKeyword(text string) bool {
+	t := p.peek()
+	return !p.done() && t.kind == tokName && t.text == text
+}
+
+func (p *xpathParser) expectOp(text string) error {
+	if !p.isOp(text) {
+		return fmt.Errorf("expected %q", text)
+	}
+
+	p.pos++
+	return nil
+}
+
+func (p *xpathParser) parseOr() (xpathExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpathBinary{op: "or", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *xpathParser) parseAnd() (xpathExpr, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.pos++
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpathBinary{op: "and", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *xpathParser) parseEquality() (xpathExpr, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("=") || p.isOp("!=") {
+		op := p.peek().text
+		p.pos++
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpathBinary{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *xpathParser) parseRelational() (xpathExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("<") || p.isOp(">") || p.isOp("<=") || p.isOp(">=") {
+		op := p.peek().text
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpathBinary{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *xpathParser) parseUnary() (xpathExpr, error) {
+	if p.isOp("-") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &xpathNegate{operand: operand}, nil
+	}
+
+	return p.parseUnion()
+}
+
+func (p *xpathParser) parseUnion() (xpathExpr, error) {
+	first, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isOp("|") {
+		return first, nil
+	}
+
+	union := &xpathUnion{parts: []xpathExpr{first}}
+	for p.isOp("|") {
+		p.pos++
+		next, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		union.parts = append(union.parts, next)
+	}
+
+	return union, nil
+}
+
+// parsePath parses a location path or a filter expression followed by steps
+func (p *xpathParser) parsePath() (xpathExpr, error) {
+	path := &xpathPath{}
+
+	switch {
+	case p.isOp("/"):
+		p.pos++
+		path.absolute = true
+		if !p.startsStep() {
+			return path, nil
+		}
+
+	case p.isOp("//"):
+		p.pos++
+		path.absolute = true
+		path.steps = append(path.steps, descendantOrSelfStep())
+
+	case p.startsPrimary():
+		primary, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		preds, err := p.parsePredicates()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(preds) == 0 && !p.isOp("/") && !p.isOp("//") {
+			return primary, nil
+		}
+
+		path.filter = primary
+		path.filterPreds = preds
+		if !p.isOp("/") && !p.isOp("//") {
+			return path, nil
+		}
+
+		if p.isOp("//") {
+			path.steps = append(path.steps, descendantOrSelfStep())
+		}
+		p.pos++
+	}
+
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+
+		switch {
+		case p.isOp("/"):
+			p.pos++
+		case p.isOp("//"):
+			p.pos++
+			path.steps = append(path.steps, descendantOrSelfStep())
+		default:
+			return path, nil
+		}
+	}
+}
+
+// startsStep reports whether the next token can begin a location step
+func (p *xpathParser) startsStep() bool {
+	if p.done() {
+		return false
+	}
+
+	t := p.peek()
+	if t.kind == tokName {
+		return true
+	}
+
+	return t.kind == tokOp && (t.text == "@" || t.text == "." || t.text == ".." || t.text == "*")
+}
+
+// startsPrimary reports whether the next token begins a literal, a number,
+// a parenthesised expression or a function call
+func (p *xpathParser) startsPrimary() bool {
+	t := p.peek()
+	switch {
+	case p.done():
+		return false
+	case t.kind == tokString || t.kind == tokNumber:
+		return true
+	case t.kind == tokOp && t.text == "(":
+		return true
+	case t.kind == tokName:
+		next := p.peekAt(1)
+		return next.kind == tokOp && next.text == "(" && !isNodeTypeTest(t.text)
+	}
+
+	return false
+}
+
+func (p *xpathParser) parsePrimary() (xpathExpr, error) {
+	t := p.peek()
+	p.pos++
+
+	switch t.kind {
+	case tokString:
+		return xpathLiteral{value: t.text}, nil
+
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return xpathLiteral{value: f}, nil
+
+	case tokName:
+		call := &xpathCall{name: t.text}
+		if err := p.expectOp("("); err != nil {
+			return nil, err
+		}
+
+		for !p.isOp(")") {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+
+			if p.isOp(",") {
+				p.pos++
+			} else if !p.isOp(")") {
+				return nil, fmt.Errorf("expected ',' or ')' in call to %s()", t.text)
+			}
+		}
+		p.pos++
+
+		if _, ok := xpathFunctions[call.name]; !ok {
+			return nil, fmt.Errorf("unsupported function %s()", call.name)
+		}
+		return call, nil
+	}
+
+	// Parenthesised expression
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+
+	return expr, nil
+}
+
+func (p *xpathParser) parseStep() (xpathStep, error) {
+	step := xpathStep{axis: "child"}
+
+	switch {
+	case p.isOp("."):
+		p.pos++
+		return xpathStep{axis: "self", test: nodeTest{kind: "node"}}, nil
+
+	case p.isOp(".."):
+		p.pos++
+		return xpathStep{axis: "parent", test: nodeTest{kind: "node"}}, nil
+
+	case p.isOp("@"):
+		p.pos++
+		step.axis = "attribute"
+
+	case p.peek().kind == tokName && p.peekAt(1).kind == tokOp && p.peekAt(1).text == "::":
+		step.axis = p.peek().text
+		if !xpathAxes[step.axis] {
+			return step, fmt.Errorf("unsupported axis %s::", step.axis)
+		}
+		p.pos += 2
+	}
+
+	test, err := p.parseNodeTest()
+	if err != nil {
+		return step, err
+	}
+	step.test = test
+
+	step.preds, err = p.parsePredicates()
+	return step, err
+}
+
+func (p *xpathParser) parseNodeTest() (nodeTest, error) {
+	t := p.peek()
+
+	switch {
+	case t.kind == tokOp && t.text == "*":
+		p.pos++
+		return nodeTest{kind: "name", local: "*"}, nil
+
+	case t.kind == tokName:
+		p.pos++
+
+		if isNodeTypeTest(t.text) && p.isOp("(") {
+			p.pos++
+			if err := p.expectOp(")"); err != nil {
+				return nodeTest{}, err
+			}
+			return nodeTest{kind: t.text}, nil
+		}
+
+		prefix, local, ok := strings.Cut(t.text, ":")
+		if !ok {
+			prefix, local = "", t.text
+		}
+		return nodeTest{kind: "name", prefix: prefix, local: local}, nil
+	}
+
+	return nodeTest{}, fmt.Errorf("expected node test")
+}
+
+func (p *xpathParser) parsePredicates() ([]xpathExpr, error) {
+	var preds []xpathExpr
+
+	for p.isOp("[") {
+		p.pos++
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectOp("]"); err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+
+	return preds, nil
+}
+
+func isNodeTypeTest(name string) bool {
+	return name == "text" || name == "node" || name == "comment"
+}
+
+// xpathAxes are the axes that can be written out in full
+var xpathAxes = map[string]bool{
+	"child": true, "descendant": true, "descendant-or-self": true, "parent": true,
+	"ancestor": true, "ancestor-or-self": true, "following-sibling": true,
+	"preceding-sibling": true, "self": true, "attribute": true,
+}
+
+func descendantOrSelfStep() xpathStep {
+	return xpathStep{axis: "descendant-or-self", test: nodeTest{kind: "node"}}
+}
+
+// Expressions
+
+type xpathLiteral struct {
+	value interface{}
+}
+
+func (l xpathLiteral) eval(*xpathContext) interface{} {
+	return l.value
+}
+
+type xpathNegate struct {
+	operand xpathExpr
+}
+
+func (n *xpathNegate) eval(ctx *xpathContext) interface{} {
+	return -xpathNumber(n.operand.eval(ctx))
+}
+
+type xpathUnion struct {
+	parts []xpathExpr
+}
+
+func (u *xpathUnion) eval(ctx *xpathContext) interface{} {
+	var items []xpathItem
+	for _, part := range u.parts {
+		if set, ok := part.eval(ctx).([]xpathItem); ok {
+			items = append(items, set...)
+		}
+	}
+
+	return dedupeItems(items)
+}
+
+type xpathBinary struct {
+	op          string
+	left, right xpathExpr
+}
+
+func (b *xpathBinary) eval(ctx *xpathContext) interface{} {
+	switch b.op {
+	case "or":
+		return xpathBool(b.left.eval(ctx)) || xpathBool(b.right.eval(ctx))
+	case "and":
+		return xpathBool(b.left.eval(ctx)) && xpathBool(b.right.eval(ctx))
+	}
+
+	return xpathCompare(b.op, b.left.eval(ctx), b.right.eval(ctx))
+}
+
+// nodeTest selects nodes on an axis by kind and name
+type nodeTest struct {
+	kind   string // "name", "text", "node" or "comment"
+	prefix string
+	local  string // "*" matches any name
+}
+
+type xpathStep struct {
+	axis  string
+	test  nodeTest
+	preds []xpathExpr
+}
+
+type xpathPath struct {
+	absolute    bool
+	filter      xpathExpr
+	filterPreds []xpathExpr
+	steps       []xpathStep
+}
+
+func (p *xpathPath) eval(ctx *xpathContext) interface{} {
+	var current []xpathItem
+
+	switch {
+	case p.filter != nil:
+		set, ok := p.filter.eval(ctx).([]xpathItem)
+		if !ok {
+			return []xpathItem{}
+		}
+		current = applyPredicates(set, p.filterPreds, ctx.namespaces)
+	case p.absolute:
+		root := ctx.item.node
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		current = []xpathItem{{node: root}}
+	default:
+		current = []xpathItem{ctx.item}
+	}
+
+	for _, step := range p.steps {
+		var next []xpathItem
+		for _, item := range current {
+			candidates := step.candidates(item, ctx.namespaces)
+			next = append(next, applyPredicates(candidates, step.preds, ctx.namespaces)...)
+		}
+		current = dedupeItems(next)
+	}
+
+	return current
+}
+
+// applyPredicates filters a node set through each predicate in turn
+func applyPredicates(items []xpathItem, preds []xpathExpr, namespaces map[string]string) []xpathItem {
+	for _, pred := range preds {
+		var kept []xpathItem
+		for i, item := range items {
+			ctx := &xpathContext{item: item, pos: i + 1, size: len(items), namespaces: namespaces}
+			result := pred.eval(ctx)
+
+			// A numeric predicate selects by position
+			if n, ok := result.(float64); ok {
+				if int(n) == i+1 && n == math.Trunc(n) {
+					kept = append(kept, item)
+				}
+				continue
+			}
+
+			if xpathBool(result) {
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+
+	return items
+}
+
+// candidates returns the nodes on the step's axis that pass its node test
+func (s *xpathStep) candidates(item xpathItem, namespaces map[string]string) []xpathItem {
+	var result []xpathItem
+
+	add := func(n *xmlNode) {
+		if s.test.matchesNode(n, namespaces) {
+			result = append(result, xpathItem{node: n})
+		}
+	}
+
+	node := item.node
+	switch s.axis {
+	case "self":
+		if item.attr != nil {
+			if s.test.kind == "node" {
+				result = append(result, item)
+			}
+			return result
+		}
+		add(node)
+
+	case "child":
+		if item.attr == nil {
+			for _, c := range node.Children {
+				add(c)
+			}
+		}
+
+	case "descendant", "descendant-or-self":
+		if item.attr != nil {
+			if s.axis == "descendant-or-self" && s.test.kind == "node" {
+				result = append(result, item)
+			}
+			return result
+		}
+
+		if s.axis == "descendant-or-self" {
+			add(node)
+		}
+
+		var walk func(*xmlNode)
+		walk = func(n *xmlNode) {
+			for _, c := range n.Children {
+				add(c)
+				walk(c)
+			}
+		}
+		walk(node)
+
+	case "parent":
+		if item.attr != nil {
+			add(node)
+		} else if node.Parent != nil {
+			add(node.Parent)
+		}
+
+	case "ancestor", "ancestor-or-self":
+		if s.axis == "ancestor-or-self" {
+			if item.attr != nil {
+				if s.test.kind == "node" {
+					result = append(result, item)
+				}
+			} else {
+				add(node)
+			}
+		}
+
+		start := node.Parent
+		if item.attr != nil {
+			start = node
+		}
+
+		for a := start; a != nil; a = a.Parent {
+			add(a)
+		}
+
+	case "following-sibling", "preceding-sibling":
+		if item.attr != nil || node.Parent == nil {
+			return result
+		}
+
+		siblings := node.Parent.Children
+		idx := -1
+		for i, c := range siblings {
+			if c == node {
+				idx = i
+				break
+			}
+		}
+
+		if s.axis == "following-sibling" {
+			for _, c := range siblings[idx+1:] {
+				add(c)
+			}
+		} else {
+			for i := idx - 1; i >= 0; i-- {
+				add(siblings[i])
+			}
+		}
+
+	case "attribute":
+		if item.attr != nil || !node.IsElement() {
+			return result
+		}
+
+		for i := range node.Attrs {
+			attr := &node.Attrs[i]
+			if s.test.matchesAttr(attr, namespaces) {
+				result = append(result, xpathItem{node: node, attr: attr})
+			}
+		}
+	}
+
+	return result
+}
+
+// matchesNode applies the test to an element, text or document node. An
+// unprefixed name matches the local name in any namespace, which keeps
+// mappings readable for documents with a default namespace. A prefixed name
+// matches on the namespace URI when the source declares the prefix, and on
+// the prefix written in the document otherwise.
+func (t *nodeTest) matchesNode(n *xmlNode, namespaces map[string]string) bool {
+	switch t.kind {
+	case "node":
+		return true
+	case "text":
+		return !n.IsElement() && n.Parent != nil
+	case "comment":
+		return false
+	}
+
+	if !n.IsElement() {
+		return false
+	}
+
+	return t.matchesName(n.Prefix, n.Space, n.Local, namespaces)
+}
+
+func (t *nodeTest) matchesAttr(a *xmlAttr, namespaces map[string]string) bool {
+	if t.kind == "node" {
+		return true
+	}
+
+	if t.kind != "name" {
+		return false
+	}
+
+	return t.matchesName(a.Prefix, a.Space, a.Local, namespaces)
+}
+
+func (t *nodeTest) matchesName(prefix, space, local string, namespaces map[string]string) bool {
+	if t.local != "*" && t.local != local {
+		return false
+	}
+
+	if t.prefix == "" {
+		return true
+	}
+
+	if uri, ok := namespaces[t.prefix]; ok {
+		return space == uri
+	}
+
+	return prefix == t.prefix
+}
+
+// dedupeItems removes duplicate items while keeping their first position
+func dedupeItems(items []xpathItem) []xpathItem {
+	if len(items) < 2 {
+		return items
+	}
+
+	seen := make(map[xpathItem]bool, len(items))
+	result := items[:0:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// Functions
+
+type xpathCall struct {
+	name string
+	args []xpathExpr
+}
+
+func (c *xpathCall) eval(ctx *xpathContext) interface{} {
+	args := make([]interface{}, len(c.args))
+	for i, arg := range c.args {
+		args[i] = arg.eval(ctx)
+	}
+
+	return xpathFunctions[c.name](ctx, args)
+}
+
+// contextString returns the string value of the first argument, or of the context node
+func contextString(ctx *xpathContext, args []interface{}) string {
+	if len(args) > 0 {
+		return xpathString(args[0])
+	}
+
+	return ctx.item.String()
+}
+
+// stringArg returns the string value of the argument at index i
+func stringArg(args []interface{}, i int) string {
+	if i < len(args) {
+		return xpathString(args[i])
+	}
+
+	return ""
+}
+
+var xpathFunctions map[string]func(*xpathContext, []interface{}) interface{}
+
+func init() {
+	xpathFunctions = map[string]func(*xpathContext, []interface{}) interface{}{
+		"last": func(ctx *xpathContext, _ []interface{}) interface{} {
+			return float64(ctx.size)
+		},
+		"position": func(ctx *xpathContext, _ []interface{}) interface{} {
+			return float64(ctx.pos)
+		},
+		"count": func(_ *xpathContext, args []interface{}) interface{} {
+			if len(args) > 0 {
+				if set, ok := args[0].([]xpathItem); ok {
+					return float64(len(set))
+				}
+			}
+			return float64(0)
+		},
+		"name": func(ctx *xpathContext, args []interface{}) interface{} {
+			item, ok := firstItem(ctx, args)
+			if !ok {
+				return ""
+			}
+			if item.attr != nil {
+				if item.attr.Prefix != "" {
+					return item.attr.Prefix + ":" + item.attr.Local
+				}
+				return item.attr.Local
+			}
+			return item.node.QName()
+		},
+		"local-name": func(ctx *xpathContext, args []interface{}) interface{} {
+			item, ok := firstItem(ctx, args)
+			if !ok {
+				return ""
+			}
+			if item.attr != nil {
+				return item.attr.Local
+			}
+			return item.node.Local
+		},
+		"string": func(ctx *xpathContext, args []interface{}) interface{} {
+			return contextString(ctx, args)
+		},
+		"normalize-space": func(ctx *xpathContext, args []interface{}) interface{} {
+			return strings.Join(strings.Fields(contextString(ctx, args)), " ")
+		},
+		"string-length": func(ctx *xpathContext, args []interface{}) interface{} {
+			return float64(len([]rune(contextString(ctx, args))))
+		},
+		"concat": func(_ *xpathContext, args []interface{}) interface{} {
+			var sb strings.Builder
+			for _, arg := range args {
+				sb.WriteString(xpathString(arg))
+			}
+			return sb.String()
+		},
+		"contains": func(_ *xpathContext, args []interface{}) interface{} {
+			return strings.Contains(stringArg(args, 0), stringArg(args, 1))
+		},
+		"starts-with": func(_ *xpathContext, args []interface{}) interface{} {
+			return strings.HasPrefix(stringArg(args, 0), stringArg(args, 1))
+		},
+		"ends-with": func(_ *xpathContext, args []interface{}) interface{} {
+			return strings.HasSuffix(stringArg(args, 0), stringArg(args, 1))
+		},
+		"substring-before": func(_ *xpathContext, args []interface{}) interface{} {
+			before, _, _ := strings.Cut(stringArg(args, 0), stringArg(args, 1))
+			return before
+		},
+		"substring-after": func(_ *xpathContext, args []interface{}) interface{} {
+			_, after, _ := strings.Cut(stringArg(args, 0), stringArg(args, 1))
+			return after
+		},
+		"translate": func(_ *xpathContext, args []interface{}) interface{} {
+			from, to := []rune(stringArg(args, 1)), []rune(stringArg(args, 2))
+			return strings.Map(func(r rune) rune {
+				for i, f := range from {
+					if f == r {
+						if i < len(to) {
+							return to[i]
+						}
+						return -1
+					}
+				}
+				return r
+			}, stringArg(args, 0))
+		},
+		"not": func(_ *xpathContext, args []interface{}) interface{} {
+			return len(args) == 0 || !xpathBool(args[0])
+		},
+		"boolean": func(_ *xpathContext, args []interface{}) interface{} {
+			return len(args) > 0 && xpathBool(args[0])
+		},
+		"number": func(ctx *xpathContext, args []interface{}) interface{} {
+			if len(args) > 0 {
+				return xpathNumber(args[0])
+			}
+			return xpathNumber(ctx.item.String())
+		},
+		"true": func(*xpathContext, []interface{}) interface{} {
+			return true
+		},
+		"false": func(*xpathContext, []interface{}) interface{} {
+			return false
+		},
+	}
+}
+
+// firstItem returns the first node of the argument set, or the context node
+func firstItem(ctx *xpathContext, args []interface{}) (xpathItem, bool) {
+	if len(args) == 0 {
+		return ctx.item, true
+	}
+
+	set, ok := args[0].([]xpathItem)
+	if !ok || len(set) == 0 {
+		return xpathItem{}, false
+	}
+
+	return set[0], true
+}
+
+// Conversions
+
+func xpathString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		if t == math.Trunc(t) && !math.IsInf(t, 0) {
+			return strconv.FormatInt(int64(t), 10)
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []xpathItem:
+		if len(t) == 0 {
+			return ""
+		}
+		return t[0].String()
+	}
+
+	return ""
+}
+
+func xpathNumber(v interface{}) float64 {
+	switch t := v.(type) {
+	case float64:
+		return t
+	case bool:
+		if t {
+			return 1
+		}
+		return 0
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(xpathString(v)), 64)
+	if err != nil {
+		return math.NaN()
+	}
+
+	return f
+}
+
+func xpathBool(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case float64:
+		return t != 0 && !math.IsNaN(t)
+	case string:
+		return t != ""
+	case []xpathItem:
+		return len(t) > 0
+	}
+
+	return false
+}
+
+// xpathCompare implements the XPath comparison rules, where a node set
+// compares true if any of its nodes does
+func xpathCompare(op string, left, right interface{}) bool {
+	if set, ok := left.([]xpathItem); ok {
+		for _, item := range set {
+			if xpathCompare(op, item.String(), right) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if set, ok := right.([]xpathItem); ok {
+		for _, item := range set {
+			if xpathCompare(op, left, item.String()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if op == "=" || op == "!=" {
+		var equal bool
+		switch {
+		case isBool(left) || isBool(right):
+			equal = xpathBool(left) == xpathBool(right)
+		case isNumber(left) || isNumber(right):
+			equal = xpathNumber(left) == xpathNumber(right)
+		default:
+			equal = xpathString(left) == xpathString(right)
+		}
+		return equal == (op == "=")
+	}
+
+	l, r := xpathNumber(left), xpathNumber(right)
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+
+	return false
+}
+
+func isBool(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := v.(float64)
+	return ok
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	from := time.Date(2024, 3, 15, 10, 7, 30, 0, time.UTC) // A Friday
+
+	tests := []struct {
+		spec string
+		want []string // The next run times after from
+	}{
+		{"*/15 * * * *", []string{"2024-03-15 10:15", "2024-03-15 10:30"}},
+		{"0 9-17/4 * * mon-fri", []string{"2024-03-15 13:00", "2024-03-15 17:00", "2024-03-18 09:00"}},
+		{"30 6 1,15 * *", []string{"2024-04-01 06:30", "2024-04-15 06:30"}},
+		{"0 0 * * 7", []string{"2024-03-17 00:00"}},
+		{"0 0 13 * fri", []string{"2024-03-22 00:00", "2024-03-29 00:00", "2024-04-05 00:00", "2024-04-12 00:00", "2024-04-13 00:00"}},
+		{"0 12 29 feb *", []string{"2028-02-29 12:00"}},
+		{"@daily", []string{"2024-03-16 00:00"}},
+		{"@every 90m", []string{"2024-03-15 11:37", "2024-03-15 13:07"}},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.spec, err)
+			continue
+		}
+
+		next := from
+		for _, want := range tt.want {
+			next = schedule.Next(next)
+			if got := next.Format("2006-01-02 15:04"); got != want {
+				t.Errorf("Parse(%q) next run = %s, want %s", tt.spec, got, want)
+				break
+			}
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * 13 *", "5-1 * * * *", "*/0 * * * *", "@often", "@every 0s"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
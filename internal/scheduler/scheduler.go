@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is the work run on a schedule. It should return once ctx is cancelled.
+type Job func(ctx context.Context)
+
+// Entry is a snapshot of a scheduled job
+type Entry struct {
+	Name     string    `json:"name"`
+	Spec     string    `json:"schedule"`
+	Next     time.Time `json:"next_run"`
+	Prev     time.Time `json:"last_run,omitempty"`
+	Running  bool      `json:"running"`
+	Skipped  int       `json:"skipped"` // Runs skipped because the previous one was still going
+	Disabled bool      `json:"disabled,omitempty"`
+}
+
+type entry struct {
+	Entry
+	schedule Schedule
+	job      Job
+}
+
+// Scheduler runs jobs on their schedules. A job is never run twice at once:
+// when it is due while its previous run is still going, that run is skipped.
+type Scheduler struct {
+	entries map[string]*entry
+	wake    chan struct{}
+	running sync.WaitGroup
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+// New creates an empty scheduler
+func New() *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*entry),
+		wake:    make(chan struct{}, 1),
+		now:     time.Now,
+	}
+}
+
+// Add schedules a job under a unique name. The spec is parsed by Parse.
+func (s *Scheduler) Add(name, spec string, job Job) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[name]; exists {
+		return fmt.Errorf("job %q is already scheduled", name)
+	}
+
+	e := &entry{
+		Entry:    Entry{Name: name, Spec: spec},
+		schedule: schedule,
+		job:      job,
+	}
+	e.Next = schedule.Next(s.now())
+	e.Disabled = e.Next.IsZero()
+	s.entries[name] = e
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Entries returns the scheduled jobs, soonest first
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e.Entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Next.Equal(entries[j].Next) {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[j].Next.IsZero() || !entries[i].Next.IsZero() && entries[i].Next.Before(entries[j].Next)
+	})
+
+	return entries
+}
+
+// Run starts due jobs until ctx is cancelled, then waits for the running
+// jobs to return
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.running.Wait()
+
+	for {
+		s.mu.Lock()
+		now := s.now()
+		var next time.Time
+
+		for _, e := range s.entries {
+			if e.Disabled {
+				continue
+			}
+
+			if !e.Next.After(now) {
+				s.start(ctx, e, now)
+			}
+
+			if next.IsZero() || e.Next.Before(next) {
+				next = e.Next
+			}
+		}
+		s.mu.Unlock()
+
+		// With nothing scheduled, wait for a job to be added
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(next.Sub(now))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-due:
+		case <-s.wake:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// start runs a due job unless its previous run is still going, and moves the
+// entry on to its next run time. The caller must hold the lock.
+func (s *Scheduler) start(ctx context.Context, e *entry, now time.Time) {
+	e.Next = e.schedule.Next(now)
+	if e.Next.IsZero() {
+		e.Disabled = true
+	}
+
+	if e.Running {
+		e.Skipped++
+		log.Printf("Skipping scheduled run of %s: previous run still in progress", e.Name)
+		return
+	}
+
+	e.Running = true
+	e.Prev = now
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+
+		e.job(ctx)
+
+		s.mu.Lock()
+		e.Running = false
+		s.mu.Unlock()
+	}()
+}
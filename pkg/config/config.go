@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -48,14 +51,15 @@ type AppConfig struct {
 
 // FetcherConfig contains settings for the fetcher components
 type FetcherConfig struct {
-	MaxConcurrentWorkers int           `yaml:"max_concurrent_workers"`
-	RequestTimeout       time.Duration `yaml:"request_timeout"`
-	MaxIdleConns         int           `yaml:"max_idle_conns"`
-	MaxConnsPerHost      int           `yaml:"max_conns_per_host"`
-	UserAgent            string        `yaml:"user_agent"`
-	RespectRobotsTxt     bool          `yaml:"respect_robots_txt"`
-	RateLimiting         RateLimit     `yaml:"rate_limiting"`
-	RetryPolicy          RetryPolicy   `yaml:"retry_policy"`
+	MaxConcurrentWorkers int            `yaml:"max_concurrent_workers"`
+	RequestTimeout       time.Duration  `yaml:"request_timeout"`
+	MaxIdleConns         int            `yaml:"max_idle_conns"`
+	MaxConnsPerHost      int            `yaml:"max_conns_per_host"`
+	UserAgent            string         `yaml:"user_agent"`
+	RespectRobotsTxt     bool           `yaml:"respect_robots_txt"`
+	RateLimiting         RateLimit      `yaml:"rate_limiting"`
+	RetryPolicy          RetryPolicy    `yaml:"retry_policy"`
+	CircuitBreaker       CircuitBreaker `yaml:"circuit_breaker"`
 }
 
 // RateLimit contains rate limiting configuration
@@ -77,6 +81,14 @@ type RetryPolicy struct {
 	RetryableErrors []string      `yaml:"retryable_errors"`
 }
 
+// CircuitBreaker contains the settings of the per-host circuit breakers
+type CircuitBreaker struct {
+	Enabled          bool          `yaml:"enabled"`
+	FailureThreshold int           `yaml:"failure_threshold"`  // Consecutive failures that open the circuit
+	Cooldown         time.Duration `yaml:"cooldown"`           // Time an open circuit fails fast before a trial request
+	HalfOpenRequests int           `yaml:"half_open_requests"` // Trial requests let through at once after the cooldown
+}
+
 // ParserConfig contains settings for the parser components
 type ParserConfig struct {
 	MaxConcurrentWorkers int                    `yaml:"max_concurrent_workers"`
@@ -86,8 +98,9 @@ type ParserConfig struct {
 }
 
 type ConConfig struct {
-	MaxFetchers int `yaml:"max_fetchers"`
-	MaxParsers  int `yaml:"max_parsers"`
+	MaxFetchers   int           `yaml:"max_fetchers"`
+	MaxParsers    int           `yaml:"max_parsers"`
+	PriorityAging time.Duration `yaml:"priority_aging"` // Wait that raises a queued job by one priority level, 0 to disable
 }
 
 type TimeConfig struct {
@@ -100,11 +113,12 @@ type HTTPConfig struct {
 	UserAgent       string `yaml:"user_agent"`       // User-Agent header for HTTP requests
 	FollowRedirects bool   `yaml:"follow_redirects"` // Whether to follow HTTP redirects
 	MaxRedirects    int    `yaml:"max_redirects"`    // Maximum number of redirects to follow
+	MaxBodySize     int    `yaml:"max_body_size"`    // MB; ceiling on a response body after decompression
 }
 
 // OutputConfig contains settings for output handling
 type OutputConfig struct {
-	Format      string          `yaml:"format"`      // json, yaml, csv, xml
+	Format      string          `yaml:"format"`      // json, yaml, csv, xml, html
 	Destination string          `yaml:"destination"` // file, stdout, api
 	FilePath    string          `yaml:"file_path"`
 	Pretty      bool            `yaml:"pretty"`
@@ -176,9 +190,9 @@ type Source struct {
 	ID        string                 `yaml:"id"`
 	Name      string                 `yaml:"name"`
 	URL       string                 `yaml:"url"`
-	Type      string                 `yaml:"type"` // html, json, xml, rss
+	Type      string                 `yaml:"type"` // html, json, xml, rss, jsonfeed, csv, tsv, auto or any registered parser type
 	Enabled   bool                   `yaml:"enabled"`
-	Schedule  string                 `yaml:"schedule"` // cron expression
+	Schedule  string                 `yaml:"schedule"` // cron expression or "@every <duration>", used in daemon mode
 	Headers   map[string]string      `yaml:"headers"`
 	Selectors SourceSelectors        `yaml:"selectors"`
 	RateLimit *RateLimit             `yaml:"rate_limit"` // Override global rate limit
@@ -186,6 +200,77 @@ type Source struct {
 	Priority  int                    `yaml:"priority"`   // Higher number = higher priority
 	Tags      []string               `yaml:"tags"`
 	Metadata  map[string]interface{} `yaml:"metadata"`
+
+	// raw keeps the source's YAML, including the parser-specific settings
+	// that are not modelled here, for Decode
+	raw *yaml.Node
+}
+
+// UnmarshalYAML decodes a source and keeps its YAML for Decode
+func (s *Source) UnmarshalYAML(node *yaml.Node) error {
+	type plain Source
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+
+	s.raw = node
+	return nil
+}
+
+// Decode decodes the source's complete YAML into v, such as the pipeline's
+// own source model with its parser, pagination and sitemap settings. A
+// source built in code rather than loaded from YAML is decoded from its
+// fields.
+func (s *Source) Decode(v interface{}) error {
+	if s.raw != nil {
+		return s.raw.Decode(v)
+	}
+
+	fields := map[string]interface{}{
+		"id":        s.ID,
+		"name":      s.Name,
+		"url":       s.URL,
+		"parser":    s.Type,
+		"enabled":   s.Enabled,
+		"priority":  s.Priority,
+		"schedule":  s.Schedule,
+		"headers":   s.Headers,
+		"selectors": s.Selectors.asMap(),
+	}
+
+	if s.Timeout != nil {
+		fields["timeout"] = s.Timeout.String()
+	}
+
+	data, err := yaml.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("error encoding source %s: %w", s.ID, err)
+	}
+
+	return yaml.Unmarshal(data, v)
+}
+
+// asMap returns the configured selectors keyed by field name, joining the
+// selectors of list fields into one selector group
+func (s SourceSelectors) asMap() map[string]string {
+	selectors := make(map[string]string)
+	for field, selector := range map[string]string{
+		"title":       s.Title,
+		"description": s.Description,
+		"content":     s.Content,
+		"author":      s.Author,
+		"date":        s.Date,
+		"categories":  strings.Join(s.Categories, ", "),
+		"tags":        strings.Join(s.Tags, ", "),
+		"images":      strings.Join(s.Images, ", "),
+		"links":       strings.Join(s.Links, ", "),
+	} {
+		if selector != "" {
+			selectors[field] = selector
+		}
+	}
+
+	return selectors
 }
 
 // SourceSelectors contains CSS/XPath selectors for extracting data
@@ -214,6 +299,12 @@ func DefaultConfig() *Config {
 				Connection: 5 * time.Second,
 				Total:      60 * time.Second,
 			},
+			Concurrency: ConConfig{
+				PriorityAging: 10 * time.Second,
+			},
+			HTTP: HTTPConfig{
+				MaxBodySize: 50,
+			},
 		},
 		Fetcher: FetcherConfig{
 			MaxConcurrentWorkers: 10,
@@ -240,6 +331,12 @@ func DefaultConfig() *Config {
 					"temporary failure",
 				},
 			},
+			CircuitBreaker: CircuitBreaker{
+				Enabled:          true,
+				FailureThreshold: 5,
+				Cooldown:         30 * time.Second,
+				HalfOpenRequests: 1,
+			},
 		},
 		Parser: ParserConfig{
 			MaxConcurrentWorkers: 5,
@@ -366,6 +463,18 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max concurrent workers must be positive")
 	}
 
+	if c.Fetcher.RetryPolicy.MaxRetries < 0 {
+		return fmt.Errorf("retry policy max retries cannot be negative")
+	}
+
+	if c.Fetcher.RetryPolicy.BackoffFactor != 0 && c.Fetcher.RetryPolicy.BackoffFactor < 1 {
+		return fmt.Errorf("retry policy backoff factor must be at least 1")
+	}
+
+	if c.Fetcher.CircuitBreaker.Enabled && c.Fetcher.CircuitBreaker.FailureThreshold <= 0 {
+		return fmt.Errorf("circuit breaker failure threshold must be positive")
+	}
+
 	if c.Fetcher.RequestTimeout <= 0 {
 		return fmt.Errorf("request timeout must be positive")
 	}
@@ -380,7 +489,7 @@ func (c *Config) Validate() error {
 	}
 
 	// Validate output config
-	validFormats := []string{"json", "yaml", "csv", "xml"}
+	validFormats := []string{"json", "yaml", "csv", "xml", "html"}
 	if !contains(validFormats, c.Output.Format) {
 		return fmt.Errorf("invalid output format: %s", c.Output.Format)
 	}
@@ -430,8 +539,7 @@ func (s *SourcesConfig) Validate() error {
 			return fmt.Errorf("source name cannot be empty for source: %s", source.ID)
 		}
 
-		validTypes := []string{"html", "json", "xml", "rss"}
-		if !contains(validTypes, source.Type) {
+		if !IsSourceType(source.Type) {
 			return fmt.Errorf("invalid source type '%s' for source: %s", source.Type, source.ID)
 		}
 	}
@@ -439,6 +547,48 @@ func (s *SourcesConfig) Validate() error {
 	return nil
 }
 
+var (
+	// sourceTypes holds the source types accepted by validation. Parsers
+	// registered at runtime add their type through RegisterSourceType.
+	sourceTypes = map[string]bool{
+		"html": true,
+		"json": true,
+		"xml":  true,
+		"rss":  true,
+	}
+	sourceTypesMu sync.RWMutex
+)
+
+// RegisterSourceType adds a source type accepted by validation
+func RegisterSourceType(sourceType string) {
+	sourceTypesMu.Lock()
+	defer sourceTypesMu.Unlock()
+
+	sourceTypes[sourceType] = true
+}
+
+// IsSourceType reports whether the source type is known
+func IsSourceType(sourceType string) bool {
+	sourceTypesMu.RLock()
+	defer sourceTypesMu.RUnlock()
+
+	return sourceTypes[sourceType]
+}
+
+// SourceTypes returns all known source types, sorted
+func SourceTypes() []string {
+	sourceTypesMu.RLock()
+	defer sourceTypesMu.RUnlock()
+
+	types := make([]string, 0, len(sourceTypes))
+	for sourceType := range sourceTypes {
+		types = append(types, sourceType)
+	}
+
+	sort.Strings(types)
+	return types
+}
+
 // GetEnabledSources returns only enabled sources
 func (s *SourcesConfig) GetEnabledSources() []Source {
 	var enabled []Source
@@ -2,6 +2,7 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -32,15 +33,38 @@ func (s *APIServer) Start() error {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/api/results", func(w http.ResponseWriter, r *http.Request) {
-		results, err := s.aggregator.Run(r.Context())
+		// A daemon serves the results of its latest runs instead of running again
+		results, daemon := s.aggregator.Latest()
+
+		if !daemon {
+			var err error
+			results, err = s.aggregator.Run(r.Context())
+
+			// The daemon may have started in the meantime
+			if errors.Is(err, aggregator.ErrDaemonRunning) {
+				results, daemon = s.aggregator.Latest()
+			}
+
+			if err != nil && !daemon {
+				http.Error(w, "Failed to get results", http.StatusInternalServerError)
+				return
+			}
+		}
 
-		if err != nil {
-			http.Error(w, "Failed to get results", http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+	})
+
+	mux.HandleFunc("/api/schedule", func(w http.ResponseWriter, r *http.Request) {
+		entries := s.aggregator.Schedule()
+
+		if entries == nil {
+			http.Error(w, "Not running as a daemon", http.StatusNotFound)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(results)
+		json.NewEncoder(w).Encode(entries)
 	})
 
 	server := &http.Server{